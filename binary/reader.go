@@ -4,7 +4,7 @@ package binary
 import (
 	"bufio"
 	"encoding/binary"
-	"fmt"
+	"io"
 	"os"
 )
 
@@ -108,7 +108,7 @@ func (fh *File) String() (string, error) {
 		return "", err
 	}
 	out := make([]byte, strLen, strLen)
-	if _, err := fh.Read(out); err != nil {
+	if _, err := io.ReadFull(fh, out); err != nil {
 		return "", err
 	}
 	return string(out), nil
@@ -167,6 +167,14 @@ func OpenBufferedFile(path string) (*BufferedFile, error) {
 	return bufFh, nil
 }
 
+// NewBufferedReader creates BufferedFile reading from given reader, e.g.
+// decompressed stream
+func NewBufferedReader(r io.Reader) *BufferedFile {
+	bufFh := new(BufferedFile)
+	bufFh.Reset(r)
+	return bufFh
+}
+
 // Double reads float64 from BufferedFile
 func (fh *BufferedFile) Double() (float64, error) {
 	var out float64
@@ -178,8 +186,7 @@ func (fh *BufferedFile) Double() (float64, error) {
 
 // Discard n bytes
 func (fh *BufferedFile) Discard(n int) (int, error) {
-	// TODO: fixme, Discarding / bath reading was bugged
-	return 0, fmt.Errorf("Not implemented")
+	return fh.Reader.Discard(n)
 }
 
 // Float reads float32 from BufferedFile
@@ -233,7 +240,7 @@ func (fh *BufferedFile) String() (string, error) {
 		return "", err
 	}
 	out := make([]byte, strLen, strLen)
-	if _, err := fh.Read(out); err != nil {
+	if _, err := io.ReadFull(fh, out); err != nil {
 		return "", err
 	}
 	return string(out), nil
@@ -0,0 +1,100 @@
+package binary
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Writer interface for binary files
+type Writer interface {
+	PutDouble(float64) error
+	PutFloat(float32) error
+	PutInt8(int8) error
+	PutInt16(int16) error
+	PutInt32(int32) error
+	PutInt64(int64) error
+	PutString(string) error
+	PutUInt8(uint8) error
+	PutUInt16(uint16) error
+	PutUInt32(uint32) error
+	PutUInt64(uint64) error
+}
+
+// BufferedWriter extends bufio.Writer implementing Writer interface
+type BufferedWriter struct {
+	bufio.Writer
+}
+
+// NewBufferedWriter creates BufferedWriter writing to given io.Writer. Flush
+// has to be called after last write
+func NewBufferedWriter(w io.Writer) *BufferedWriter {
+	bufW := new(BufferedWriter)
+	bufW.Reset(w)
+	return bufW
+}
+
+// PutDouble writes float64 to BufferedWriter
+func (w *BufferedWriter) PutDouble(val float64) error {
+	return binary.Write(w, binary.LittleEndian, val)
+}
+
+// PutFloat writes float32 to BufferedWriter
+func (w *BufferedWriter) PutFloat(val float32) error {
+	return binary.Write(w, binary.LittleEndian, val)
+}
+
+// PutInt8 writes int8 to BufferedWriter
+func (w *BufferedWriter) PutInt8(val int8) error {
+	return binary.Write(w, binary.LittleEndian, val)
+}
+
+// PutInt16 writes int16 to BufferedWriter
+func (w *BufferedWriter) PutInt16(val int16) error {
+	return binary.Write(w, binary.LittleEndian, val)
+}
+
+// PutInt32 writes int32 to BufferedWriter
+func (w *BufferedWriter) PutInt32(val int32) error {
+	return binary.Write(w, binary.LittleEndian, val)
+}
+
+// PutInt64 writes int64 to BufferedWriter
+func (w *BufferedWriter) PutInt64(val int64) error {
+	return binary.Write(w, binary.LittleEndian, val)
+}
+
+// PutString writes string to BufferedWriter
+// Function first writes uint16 string length N, then N bytes of string
+func (w *BufferedWriter) PutString(val string) error {
+	if len(val) > math.MaxUint16 {
+		return fmt.Errorf("String of length %d exceeds maximum length", len(val))
+	}
+	if err := binary.Write(w, binary.LittleEndian, uint16(len(val))); err != nil {
+		return err
+	}
+	_, err := w.WriteString(val)
+	return err
+}
+
+// PutUInt8 writes uint8 to BufferedWriter
+func (w *BufferedWriter) PutUInt8(val uint8) error {
+	return binary.Write(w, binary.LittleEndian, val)
+}
+
+// PutUInt16 writes uint16 to BufferedWriter
+func (w *BufferedWriter) PutUInt16(val uint16) error {
+	return binary.Write(w, binary.LittleEndian, val)
+}
+
+// PutUInt32 writes uint32 to BufferedWriter
+func (w *BufferedWriter) PutUInt32(val uint32) error {
+	return binary.Write(w, binary.LittleEndian, val)
+}
+
+// PutUInt64 writes uint64 to BufferedWriter
+func (w *BufferedWriter) PutUInt64(val uint64) error {
+	return binary.Write(w, binary.LittleEndian, val)
+}
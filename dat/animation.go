@@ -0,0 +1,340 @@
+package dat
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/gif"
+	"io"
+	"math"
+	"time"
+)
+
+// Phase durations used by client for legacy animations, which have no
+// durations stored in AnimationPhases
+const (
+	ItemFrameDuration    = 500 * time.Millisecond
+	OutfitFrameDuration  = 300 * time.Millisecond
+	EffectFrameDuration  = 75 * time.Millisecond
+	MissileFrameDuration = 75 * time.Millisecond
+)
+
+// LoopPingPong is LoopCount of sprite group played forth and back forever
+const LoopPingPong = math.MaxUint32
+
+// Selection of animation phase duration, phases last between FrameA and
+// FrameB milliseconds
+const (
+	DurationMin = iota
+	DurationMax
+	DurationAverage
+)
+
+// AnimationOptions selects which sprites of thing are animated and how. Thing
+// is rendered with RenderOutfit if Outfit is set, patterns are ignored then.
+// Duration is one of DurationMin, DurationMax and DurationAverage. With
+// HonourAsync set, synchronous sprite groups start at the first phase instead
+// of StartPhase. The client reads Async byte 0 as asynchronous, any other
+// value as synchronous
+type AnimationOptions struct {
+	Group       int
+	PatternX    int
+	PatternY    int
+	PatternZ    int
+	Layers      []int
+	Outfit      *Outfit
+	Duration    int
+	HonourAsync bool
+}
+
+// Animation holds rendered animation phases of thing. LoopCount is number of
+// times animation is played, 0 means forever
+type Animation struct {
+	Frames    []*image.RGBA
+	Delays    []time.Duration
+	LoopCount int
+}
+
+// FrameGroup returns index of sprite group of given frame group type, e.g.
+// FrameGroupMoving for outfit walk cycle, or -1 if there is no such group
+func (thing *Thing) FrameGroup(frameGroupType uint8) int {
+	for i, sprGr := range thing.SpriteGroups {
+		if sprGr.FrameGroupType == frameGroupType {
+			return i
+		}
+	}
+	return -1
+}
+
+// Animate renders every animation phase of thing sprite group, starting at
+// its StartPhase
+func Animate(thing *Thing, sprites SpriteSource, opts AnimationOptions) (*Animation, error) {
+	if opts.Group < 0 || opts.Group >= len(thing.SpriteGroups) {
+		return nil, fmt.Errorf("%s %d has no sprite group %d", thing.Type, thing.ID, opts.Group)
+	}
+	sprGr := thing.SpriteGroups[opts.Group]
+	phases := sprGr.phasesCount()
+	if phases == 0 {
+		return nil, fmt.Errorf("%s %d sprite group %d has no animation phases",
+			thing.Type, thing.ID, opts.Group)
+	}
+
+	synchronous := opts.HonourAsync && sprGr.Async != 0
+	start := int(sprGr.StartPhase)
+	if synchronous || start >= phases {
+		start = 0
+	}
+	order := make([]int, 0, 2*phases)
+	for i := 0; i < phases; i++ {
+		order = append(order, (start+i)%phases)
+	}
+
+	anim := &Animation{}
+	switch sprGr.LoopCount {
+	case 0:
+	case LoopPingPong:
+		for i := len(order) - 2; i > 0; i-- {
+			order = append(order, order[i])
+		}
+	default:
+		anim.LoopCount = int(sprGr.LoopCount)
+	}
+
+	for _, phase := range order {
+		img, err := renderPhase(thing, sprites, opts, phase)
+		if err != nil {
+			return nil, err
+		}
+		anim.Frames = append(anim.Frames, img)
+		anim.Delays = append(anim.Delays, phaseDuration(thing.Type, sprGr, phase, opts.Duration))
+	}
+	return anim, nil
+}
+
+func renderPhase(thing *Thing, sprites SpriteSource, opts AnimationOptions, phase int) (*image.RGBA, error) {
+	if opts.Outfit != nil {
+		return RenderOutfit(thing, sprites, *opts.Outfit, opts.Group, phase)
+	}
+	return Render(thing, sprites, RenderOptions{
+		Group:    opts.Group,
+		PatternX: opts.PatternX,
+		PatternY: opts.PatternY,
+		PatternZ: opts.PatternZ,
+		Frame:    phase,
+		Layers:   opts.Layers,
+	})
+}
+
+// phaseDuration returns duration of animation phase stored in sprite group,
+// or legacy duration of thing type if phase has no stored duration
+func phaseDuration(typ string, sprGr *SpriteGroup, phase, selection int) time.Duration {
+	if phase >= len(sprGr.AnimationPhases) {
+		return legacyPhaseDuration(typ)
+	}
+	animPhase := sprGr.AnimationPhases[phase]
+	if animPhase.FrameA == 0 && animPhase.FrameB == 0 {
+		return legacyPhaseDuration(typ)
+	}
+	ms := animPhase.FrameA
+	switch selection {
+	case DurationMax:
+		ms = animPhase.FrameB
+	case DurationAverage:
+		ms = uint32((uint64(animPhase.FrameA) + uint64(animPhase.FrameB)) / 2)
+	}
+	return time.Duration(ms) * time.Millisecond
+}
+
+// legacyPhaseDuration returns duration of animation phases of given thing
+// type used by clients without improved animations
+func legacyPhaseDuration(typ string) time.Duration {
+	switch typ {
+	case OUTFIT:
+		return OutfitFrameDuration
+	case EFFECT:
+		return EffectFrameDuration
+	case MISSILE:
+		return MissileFrameDuration
+	}
+	return ItemFrameDuration
+}
+
+// WriteGIF writes animation as animated GIF. Frames share single palette,
+// pixels more than half transparent become fully transparent. Delays are
+// rounded to hundredths of second
+func (anim *Animation) WriteGIF(w io.Writer) error {
+	if len(anim.Frames) == 0 {
+		return fmt.Errorf("Animation has no frames")
+	}
+	pal := animationPalette(anim.Frames)
+	doc := &gif.GIF{}
+	switch anim.LoopCount {
+	case 0:
+	case 1:
+		doc.LoopCount = -1
+	default:
+		doc.LoopCount = anim.LoopCount - 1
+	}
+	for i, frame := range anim.Frames {
+		img := image.NewPaletted(frame.Rect, pal)
+		for y := frame.Rect.Min.Y; y < frame.Rect.Max.Y; y++ {
+			for x := frame.Rect.Min.X; x < frame.Rect.Max.X; x++ {
+				c := frame.RGBAAt(x, y)
+				if c.A < 128 {
+					continue
+				}
+				img.SetColorIndex(x, y, uint8(pal.Index(opaque(c))))
+			}
+		}
+		delay := int((anim.Delays[i] + 5*time.Millisecond) / (10 * time.Millisecond))
+		if delay < 2 {
+			delay = 2
+		}
+		doc.Image = append(doc.Image, img)
+		doc.Delay = append(doc.Delay, delay)
+		doc.Disposal = append(doc.Disposal, gif.DisposalBackground)
+	}
+	return gif.EncodeAll(w, doc)
+}
+
+// animationPalette returns palette of transparent color followed by all
+// colors of frames, or web safe colors if there are too many of them
+func animationPalette(frames []*image.RGBA) color.Palette {
+	pal := color.Palette{color.RGBA{}}
+	seen := make(map[color.RGBA]bool)
+	for _, frame := range frames {
+		for i := 0; i < len(frame.Pix); i += 4 {
+			if frame.Pix[i+3] < 128 {
+				continue
+			}
+			c := opaque(color.RGBA{frame.Pix[i], frame.Pix[i+1], frame.Pix[i+2], frame.Pix[i+3]})
+			if seen[c] {
+				continue
+			}
+			if len(pal) == 256 {
+				return append(color.Palette{color.RGBA{}}, palette.WebSafe...)
+			}
+			seen[c] = true
+			pal = append(pal, c)
+		}
+	}
+	return pal
+}
+
+func opaque(c color.RGBA) color.RGBA {
+	n := color.NRGBAModel.Convert(c).(color.NRGBA)
+	return color.RGBA{n.R, n.G, n.B, 255}
+}
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// WriteAPNG writes animation as animated PNG. Frames must be of the same size,
+// delays are stored in milliseconds
+func (anim *Animation) WriteAPNG(w io.Writer) error {
+	if len(anim.Frames) == 0 {
+		return fmt.Errorf("Animation has no frames")
+	}
+	bounds := anim.Frames[0].Rect
+	width, height := uint32(bounds.Dx()), uint32(bounds.Dy())
+
+	if _, err := w.Write(pngSignature); err != nil {
+		return err
+	}
+	ihdr := make([]byte, 13)
+	binary.BigEndian.PutUint32(ihdr[0:], width)
+	binary.BigEndian.PutUint32(ihdr[4:], height)
+	ihdr[8] = 8 // bit depth
+	ihdr[9] = 6 // truecolor with alpha
+	if err := writePNGChunk(w, "IHDR", ihdr); err != nil {
+		return err
+	}
+	actl := make([]byte, 8)
+	binary.BigEndian.PutUint32(actl[0:], uint32(len(anim.Frames)))
+	binary.BigEndian.PutUint32(actl[4:], uint32(anim.LoopCount))
+	if err := writePNGChunk(w, "acTL", actl); err != nil {
+		return err
+	}
+
+	var seq uint32
+	for i, frame := range anim.Frames {
+		if frame.Rect.Dx() != bounds.Dx() || frame.Rect.Dy() != bounds.Dy() {
+			return fmt.Errorf("Frame %d size %v differs from %v", i, frame.Rect.Size(), bounds.Size())
+		}
+		delay := anim.Delays[i] / time.Millisecond
+		if delay > math.MaxUint16 {
+			delay = math.MaxUint16
+		}
+		fctl := make([]byte, 26)
+		binary.BigEndian.PutUint32(fctl[0:], seq)
+		binary.BigEndian.PutUint32(fctl[4:], width)
+		binary.BigEndian.PutUint32(fctl[8:], height)
+		binary.BigEndian.PutUint16(fctl[20:], uint16(delay))
+		binary.BigEndian.PutUint16(fctl[22:], 1000)
+		fctl[24] = 1 // dispose to background
+		if err := writePNGChunk(w, "fcTL", fctl); err != nil {
+			return err
+		}
+		seq++
+
+		data, err := compressPNGFrame(frame)
+		if err != nil {
+			return err
+		}
+		if i == 0 {
+			err = writePNGChunk(w, "IDAT", data)
+		} else {
+			fdat := make([]byte, 4, 4+len(data))
+			binary.BigEndian.PutUint32(fdat, seq)
+			err = writePNGChunk(w, "fdAT", append(fdat, data...))
+			seq++
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return writePNGChunk(w, "IEND", nil)
+}
+
+// compressPNGFrame returns zlib compressed, unfiltered, non-premultiplied
+// RGBA scanlines of image
+func compressPNGFrame(img *image.RGBA) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	zw := zlib.NewWriter(buf)
+	row := make([]byte, 1+4*img.Rect.Dx())
+	for y := img.Rect.Min.Y; y < img.Rect.Max.Y; y++ {
+		for x := img.Rect.Min.X; x < img.Rect.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.RGBAAt(x, y)).(color.NRGBA)
+			i := 1 + 4*(x-img.Rect.Min.X)
+			row[i], row[i+1], row[i+2], row[i+3] = c.R, c.G, c.B, c.A
+		}
+		if _, err := zw.Write(row); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writePNGChunk(w io.Writer, typ string, data []byte) error {
+	header := make([]byte, 8)
+	binary.BigEndian.PutUint32(header, uint32(len(data)))
+	copy(header[4:], typ)
+	crc := crc32.NewIEEE()
+	crc.Write(header[4:])
+	crc.Write(data)
+	footer := make([]byte, 4)
+	binary.BigEndian.PutUint32(footer, crc.Sum32())
+	for _, part := range [][]byte{header, data, footer} {
+		if _, err := w.Write(part); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package dat
+
+import (
+	"image"
+	"image/color"
+	"testing"
+	"time"
+)
+
+// idSprites renders every sprite filled with color holding its ID in red
+type idSprites struct{}
+
+func (idSprites) GetSprite(id int) (*image.RGBA, error) {
+	img := image.NewRGBA(image.Rect(0, 0, 32, 32))
+	for i := 0; i < len(img.Pix); i += 4 {
+		img.Pix[i], img.Pix[i+3] = uint8(id), 255
+	}
+	return img, nil
+}
+
+func TestAnimateAsync(t *testing.T) {
+	thing := NewThing(1, EFFECT)
+	sprGr := newTestSpriteGroup(1, 1, 1, 1, 1, 1, 3, 1)
+	sprGr.StartPhase = 2
+	sprGr.AnimationPhases[0] = &AnimationPhase{100, 200}
+	sprGr.AnimationPhases[1] = &AnimationPhase{300, 400}
+	sprGr.AnimationPhases[2] = &AnimationPhase{0, 0}
+	thing.SpriteGroups = []*SpriteGroup{sprGr}
+
+	tests := []struct {
+		async  uint8
+		first  uint8
+		delays []time.Duration
+	}{
+		// client reads Async byte 0 as asynchronous, which honours StartPhase
+		{0, 3, []time.Duration{EffectFrameDuration, 100 * time.Millisecond, 300 * time.Millisecond}},
+		{1, 1, []time.Duration{100 * time.Millisecond, 300 * time.Millisecond, EffectFrameDuration}},
+	}
+	for _, test := range tests {
+		sprGr.Async = test.async
+		anim, err := Animate(thing, idSprites{}, AnimationOptions{HonourAsync: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if first := anim.Frames[0].RGBAAt(0, 0); first != (color.RGBA{test.first, 0, 0, 255}) {
+			t.Errorf("async=%d: first frame shows sprite %d, want %d", test.async, first.R, test.first)
+		}
+		for i, delay := range anim.Delays {
+			if delay != test.delays[i] {
+				t.Errorf("async=%d: delay %d is %v, want %v", test.async, i, delay, test.delays[i])
+			}
+		}
+	}
+}
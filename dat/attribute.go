@@ -3,6 +3,7 @@ package dat
 import (
 	"encoding/xml"
 	"fmt"
+	"reflect"
 
 	bin "github.com/go-otserv/encoding/binary"
 )
@@ -10,24 +11,31 @@ import (
 // Attribute is common interface for all Item attributes
 type Attribute interface {
 	attrName() string
+	OpCode() OpCode
 }
 
 // AttributeBase is base struct for all Item attributes
 type AttributeBase struct {
-	XMLName xml.Name `xml:"attr"`
-	Name    string   `xml:"name,attr"`
+	XMLName xml.Name `xml:"attr" json:"-"`
+	Name    string   `xml:"name,attr" json:"name"`
 }
 
+// attrName implements Attribute interface
+func (attr AttributeBase) attrName() string { return attr.Name }
+
 // Ground attribute
 // OpCode: 0
 type Ground struct {
 	AttributeBase
-	Val uint16 `xml:"val,attr"`
+	Val uint16 `xml:"val,attr" json:"val"`
 }
 
 // attrName implements Attribute interface
 func (attr Ground) attrName() string { return attr.Name }
 
+// OpCode implements Attribute interface
+func (attr Ground) OpCode() OpCode { return OpGround }
+
 // NewGround creates new Ground attribute
 func NewGround(val uint16) *Ground {
 	return &Ground{AttributeBase{Name: "ground"}, val}
@@ -42,9 +50,12 @@ type GroundBorder struct {
 // attrName implements Attribute interface
 func (attr GroundBorder) attrName() string { return attr.Name }
 
+// OpCode implements Attribute interface
+func (attr GroundBorder) OpCode() OpCode { return OpGroundBorder }
+
 // NewGroundBorder creates new GroundBorder attribute
 func NewGroundBorder() *GroundBorder {
-	return &GroundBorder{}
+	return &GroundBorder{AttributeBase{Name: "groundBorder"}}
 }
 
 // OnBottom attribute
@@ -56,6 +67,9 @@ type OnBottom struct {
 // attrName implements Attribute interface
 func (attr OnBottom) attrName() string { return attr.Name }
 
+// OpCode implements Attribute interface
+func (attr OnBottom) OpCode() OpCode { return OpOnBottom }
+
 // NewOnBottom creates new OnBottom attribute
 func NewOnBottom() *OnBottom {
 	return &OnBottom{AttributeBase{Name: "onBottom"}}
@@ -70,6 +84,9 @@ type OnTop struct {
 // attrName implements Attribute interface
 func (attr OnTop) attrName() string { return attr.Name }
 
+// OpCode implements Attribute interface
+func (attr OnTop) OpCode() OpCode { return OpOnTop }
+
 // NewOnTop creates new OnTop attribute
 func NewOnTop() *OnTop {
 	return &OnTop{AttributeBase{Name: "onTop"}}
@@ -84,6 +101,9 @@ type Container struct {
 // attrName implements Attribute interface
 func (attr Container) attrName() string { return attr.Name }
 
+// OpCode implements Attribute interface
+func (attr Container) OpCode() OpCode { return OpContainer }
+
 // NewContainer creates new Container attribute
 func NewContainer() *Container {
 	return &Container{AttributeBase{Name: "container"}}
@@ -98,6 +118,9 @@ type Stackable struct {
 // attrName implements Attribute interface
 func (attr Stackable) attrName() string { return attr.Name }
 
+// OpCode implements Attribute interface
+func (attr Stackable) OpCode() OpCode { return OpStackable }
+
 // NewStackable creates new Stackable attribute
 func NewStackable() *Stackable {
 	return &Stackable{AttributeBase{Name: "stackable"}}
@@ -112,6 +135,9 @@ type ForceUse struct {
 // attrName implements Attribute interface
 func (attr ForceUse) attrName() string { return attr.Name }
 
+// OpCode implements Attribute interface
+func (attr ForceUse) OpCode() OpCode { return OpForceUse }
+
 // NewForceUse creates new ForceUse attribute
 func NewForceUse() *ForceUse {
 	return &ForceUse{AttributeBase{Name: "forceUse"}}
@@ -126,6 +152,9 @@ type MultiUse struct {
 // attrName implements Attribute interface
 func (attr MultiUse) attrName() string { return attr.Name }
 
+// OpCode implements Attribute interface
+func (attr MultiUse) OpCode() OpCode { return OpMultiUse }
+
 // NewMultiUse creates new MultiUse attribute
 func NewMultiUse() *MultiUse {
 	return &MultiUse{AttributeBase{Name: "multiUse"}}
@@ -135,12 +164,15 @@ func NewMultiUse() *MultiUse {
 // OpCode: 8
 type Writable struct {
 	AttributeBase
-	TextLen uint16 `xml:"textLen,attr"`
+	TextLen uint16 `xml:"textLen,attr" json:"textLen"`
 }
 
 // attrName implements Attribute interface
 func (attr Writable) attrName() string { return attr.Name }
 
+// OpCode implements Attribute interface
+func (attr Writable) OpCode() OpCode { return OpWritable }
+
 // NewWritable creates new Writable attribute
 func NewWritable(val uint16) *Writable {
 	return &Writable{AttributeBase{Name: "writable"}, val}
@@ -150,12 +182,15 @@ func NewWritable(val uint16) *Writable {
 // OpCode: 9
 type WritableOnce struct {
 	AttributeBase
-	TextLen uint16 `xml:"textLen,attr"`
+	TextLen uint16 `xml:"textLen,attr" json:"textLen"`
 }
 
 // attrName implements Attribute interface
 func (attr WritableOnce) attrName() string { return attr.Name }
 
+// OpCode implements Attribute interface
+func (attr WritableOnce) OpCode() OpCode { return OpWritableOnce }
+
 // NewWritableOnce creates new WritableOnce attribute
 func NewWritableOnce(val uint16) *WritableOnce {
 	return &WritableOnce{AttributeBase{Name: "writableOnce"}, val}
@@ -170,6 +205,9 @@ type FluidContainer struct {
 // attrName implements Attribute interface
 func (attr FluidContainer) attrName() string { return attr.Name }
 
+// OpCode implements Attribute interface
+func (attr FluidContainer) OpCode() OpCode { return OpFluidContainer }
+
 // NewFluidContainer creates new FluidContainer attribute
 func NewFluidContainer() *FluidContainer {
 	return &FluidContainer{AttributeBase{Name: "fluidContainer"}}
@@ -184,6 +222,9 @@ type Splash struct {
 // attrName implements Attribute interface
 func (attr Splash) attrName() string { return attr.Name }
 
+// OpCode implements Attribute interface
+func (attr Splash) OpCode() OpCode { return OpSplash }
+
 // NewSplash creates new Splash attribute
 func NewSplash() *Splash {
 	return &Splash{AttributeBase{Name: "splash"}}
@@ -198,6 +239,9 @@ type NotWalkable struct {
 // attrName implements Attribute interface
 func (attr NotWalkable) attrName() string { return attr.Name }
 
+// OpCode implements Attribute interface
+func (attr NotWalkable) OpCode() OpCode { return OpNotWalkable }
+
 // NewNotWalkable creates new NotWalkable attribute
 func NewNotWalkable() *NotWalkable {
 	return &NotWalkable{AttributeBase{Name: "notWalkable"}}
@@ -212,6 +256,9 @@ type NotMoveable struct {
 // attrName implements Attribute interface
 func (attr NotMoveable) attrName() string { return attr.Name }
 
+// OpCode implements Attribute interface
+func (attr NotMoveable) OpCode() OpCode { return OpNotMoveable }
+
 // NewNotMoveable creates new NotMoveable attribute
 func NewNotMoveable() *NotMoveable {
 	return &NotMoveable{AttributeBase{Name: "notMoveable"}}
@@ -226,6 +273,9 @@ type BlockProjectile struct {
 // attrName implements Attribute interface
 func (attr BlockProjectile) attrName() string { return attr.Name }
 
+// OpCode implements Attribute interface
+func (attr BlockProjectile) OpCode() OpCode { return OpBlockProjectile }
+
 // NewBlockProjectile creates new BlockProjectile attribute
 func NewBlockProjectile() *BlockProjectile {
 	return &BlockProjectile{AttributeBase{Name: "blockProjectile"}}
@@ -240,6 +290,9 @@ type NotPathable struct {
 // attrName implements Attribute interface
 func (attr NotPathable) attrName() string { return attr.Name }
 
+// OpCode implements Attribute interface
+func (attr NotPathable) OpCode() OpCode { return OpNotPathable }
+
 // NewNotPathable creates new NotPathable attribute
 func NewNotPathable() *NotPathable {
 	return &NotPathable{AttributeBase{Name: "notPathable"}}
@@ -254,6 +307,9 @@ type NoMoveAnimation struct {
 // attrName implements Attribute interface
 func (attr NoMoveAnimation) attrName() string { return attr.Name }
 
+// OpCode implements Attribute interface
+func (attr NoMoveAnimation) OpCode() OpCode { return OpNoMoveAnimation }
+
 // NewNoMoveAnimation creates new NoMoveAnimation attribute
 func NewNoMoveAnimation() *NoMoveAnimation {
 	return &NoMoveAnimation{AttributeBase{Name: "noMoveAnimation"}}
@@ -268,6 +324,9 @@ type Pickupable struct {
 // attrName implements Attribute interface
 func (attr Pickupable) attrName() string { return attr.Name }
 
+// OpCode implements Attribute interface
+func (attr Pickupable) OpCode() OpCode { return OpPickupable }
+
 // NewPickupable creates new Pickupable attribute
 func NewPickupable() *Pickupable {
 	return &Pickupable{AttributeBase{Name: "pickupable"}}
@@ -282,6 +341,9 @@ type Hangable struct {
 // attrName implements Attribute interface
 func (attr Hangable) attrName() string { return attr.Name }
 
+// OpCode implements Attribute interface
+func (attr Hangable) OpCode() OpCode { return OpHangable }
+
 // NewHangable creates new Hangable attribute
 func NewHangable() *Hangable {
 	return &Hangable{AttributeBase{Name: "hangable"}}
@@ -296,6 +358,9 @@ type HookSouth struct {
 // attrName implements Attribute interface
 func (attr HookSouth) attrName() string { return attr.Name }
 
+// OpCode implements Attribute interface
+func (attr HookSouth) OpCode() OpCode { return OpHookSouth }
+
 // NewHookSouth creates new HookSouth attribute
 func NewHookSouth() *HookSouth {
 	return &HookSouth{AttributeBase{Name: "hookSouth"}}
@@ -310,6 +375,9 @@ type HookEast struct {
 // attrName implements Attribute interface
 func (attr HookEast) attrName() string { return attr.Name }
 
+// OpCode implements Attribute interface
+func (attr HookEast) OpCode() OpCode { return OpHookEast }
+
 // NewHookEast creates new HookEast attribute
 func NewHookEast() *HookEast {
 	return &HookEast{AttributeBase{Name: "hookEast"}}
@@ -324,6 +392,9 @@ type Rotateable struct {
 // attrName implements Attribute interface
 func (attr Rotateable) attrName() string { return attr.Name }
 
+// OpCode implements Attribute interface
+func (attr Rotateable) OpCode() OpCode { return OpRotateable }
+
 // NewRotateable creates new Rotateable attribute
 func NewRotateable() *Rotateable {
 	return &Rotateable{AttributeBase{Name: "rotateable"}}
@@ -333,13 +404,16 @@ func NewRotateable() *Rotateable {
 // OpCode: 22
 type Light struct {
 	AttributeBase
-	Intensity uint16 `xml:"intensity,attr"`
-	Color     uint16 `xml:"color,attr"`
+	Intensity uint16 `xml:"intensity,attr" json:"intensity"`
+	Color     uint16 `xml:"color,attr" json:"color"`
 }
 
 // attrName implements Attribute interface
 func (attr Light) attrName() string { return attr.Name }
 
+// OpCode implements Attribute interface
+func (attr Light) OpCode() OpCode { return OpLight }
+
 // NewLight creates new Light attribute
 func NewLight(intensity, color uint16) *Light {
 	return &Light{AttributeBase{Name: "light"}, intensity, color}
@@ -354,6 +428,9 @@ type DontHide struct {
 // attrName implements Attribute interface
 func (attr DontHide) attrName() string { return attr.Name }
 
+// OpCode implements Attribute interface
+func (attr DontHide) OpCode() OpCode { return OpDontHide }
+
 // NewDontHide creates new DontHide attribute
 func NewDontHide() *DontHide {
 	return &DontHide{AttributeBase{Name: "dontHide"}}
@@ -368,6 +445,9 @@ type Translucent struct {
 // attrName implements Attribute interface
 func (attr Translucent) attrName() string { return attr.Name }
 
+// OpCode implements Attribute interface
+func (attr Translucent) OpCode() OpCode { return OpTranslucent }
+
 // NewTranslucent creates new Translucent attribute
 func NewTranslucent() *Translucent {
 	return &Translucent{AttributeBase{Name: "translucent"}}
@@ -377,13 +457,16 @@ func NewTranslucent() *Translucent {
 // OpCode: 25
 type Displacement struct {
 	AttributeBase
-	X uint16 `xml:"x,attr"`
-	Y uint16 `xml:"y,attr"`
+	X uint16 `xml:"x,attr" json:"x"`
+	Y uint16 `xml:"y,attr" json:"y"`
 }
 
 // attrName implements Attribute interface
 func (attr Displacement) attrName() string { return attr.Name }
 
+// OpCode implements Attribute interface
+func (attr Displacement) OpCode() OpCode { return OpDisplacement }
+
 // NewDisplacement creates new Displacement attribute
 func NewDisplacement(x, y uint16) *Displacement {
 	return &Displacement{AttributeBase{Name: "displacement"}, x, y}
@@ -393,12 +476,15 @@ func NewDisplacement(x, y uint16) *Displacement {
 // OpCode: 26
 type Elevation struct {
 	AttributeBase
-	Val uint16 `xml:"val,attr"`
+	Val uint16 `xml:"val,attr" json:"val"`
 }
 
 // attrName implements Attribute interface
 func (attr Elevation) attrName() string { return attr.Name }
 
+// OpCode implements Attribute interface
+func (attr Elevation) OpCode() OpCode { return OpElevation }
+
 // NewElevation creates new Elevation attribute
 func NewElevation(val uint16) *Elevation {
 	return &Elevation{AttributeBase{Name: "elevation"}, val}
@@ -413,6 +499,9 @@ type LyingCorpse struct {
 // attrName implements Attribute interface
 func (attr LyingCorpse) attrName() string { return attr.Name }
 
+// OpCode implements Attribute interface
+func (attr LyingCorpse) OpCode() OpCode { return OpLyingCorpse }
+
 // NewLyingCorpse creates new LyingCorpse attribute
 func NewLyingCorpse() *LyingCorpse {
 	return &LyingCorpse{AttributeBase{Name: "lyingCorpse"}}
@@ -427,6 +516,9 @@ type AnimateAlways struct {
 // attrName implements Attribute interface
 func (attr AnimateAlways) attrName() string { return attr.Name }
 
+// OpCode implements Attribute interface
+func (attr AnimateAlways) OpCode() OpCode { return OpAnimateAlways }
+
 // NewAnimateAlways creates new AnimateAlways attribute
 func NewAnimateAlways() *AnimateAlways {
 	return &AnimateAlways{AttributeBase{Name: "animateAlways"}}
@@ -436,12 +528,15 @@ func NewAnimateAlways() *AnimateAlways {
 // OpCode: 29
 type MinimapColor struct {
 	AttributeBase
-	Val uint16 `xml:"val,attr"`
+	Val uint16 `xml:"val,attr" json:"val"`
 }
 
 // attrName implements Attribute interface
 func (attr MinimapColor) attrName() string { return attr.Name }
 
+// OpCode implements Attribute interface
+func (attr MinimapColor) OpCode() OpCode { return OpMinimapColor }
+
 // NewMinimapColor creates new MinimapColor attribute
 func NewMinimapColor(val uint16) *MinimapColor {
 	return &MinimapColor{AttributeBase{Name: "minimapColor"}, val}
@@ -451,12 +546,15 @@ func NewMinimapColor(val uint16) *MinimapColor {
 // OpCode: 30
 type LensHelp struct {
 	AttributeBase
-	Val uint16 `xml:"val,attr"`
+	Val uint16 `xml:"val,attr" json:"val"`
 }
 
 // attrName implements Attribute interface
 func (attr LensHelp) attrName() string { return attr.Name }
 
+// OpCode implements Attribute interface
+func (attr LensHelp) OpCode() OpCode { return OpLensHelp }
+
 // NewLensHelp creates new LensHelp attribute
 func NewLensHelp(val uint16) *LensHelp {
 	return &LensHelp{AttributeBase{Name: "lensHelp"}, val}
@@ -471,6 +569,9 @@ type FullGround struct {
 // attrName implements Attribute interface
 func (attr FullGround) attrName() string { return attr.Name }
 
+// OpCode implements Attribute interface
+func (attr FullGround) OpCode() OpCode { return OpFullGround }
+
 // NewFullGround creates new FullGround attribute
 func NewFullGround() *FullGround {
 	return &FullGround{AttributeBase{Name: "fullGround"}}
@@ -485,6 +586,9 @@ type Look struct {
 // attrName implements Attribute interface
 func (attr Look) attrName() string { return attr.Name }
 
+// OpCode implements Attribute interface
+func (attr Look) OpCode() OpCode { return OpLook }
+
 // NewLook creates new Look attribute
 func NewLook() *Look {
 	return &Look{AttributeBase{Name: "look"}}
@@ -492,57 +596,51 @@ func NewLook() *Look {
 
 // Cloth attribute
 // OpCode: 33
-// Slots:
-// * 0 - both hands
-// * 1 - head
-// * 2 - necklace
-// * 3 - backpack
-// * 4 - armor
-// * 5 - shield
-// * 6 - weapon
-// * 7 - legs
-// * 8 - feet
-// * 9 - ring
-// * 10 - ammo
-// * 11 - store inbox
+// Slot is one of Slot* constants
 type Cloth struct {
 	AttributeBase
-	Slot uint16 `xml:"slot,attr"`
+	Slot ClothSlot `xml:"slot,attr" json:"slot"`
 }
 
 // attrName implements Attribute interface
 func (attr Cloth) attrName() string { return attr.Name }
 
+// OpCode implements Attribute interface
+func (attr Cloth) OpCode() OpCode { return OpCloth }
+
 // NewCloth creates new Cloth attribute
 func NewCloth(slot uint16) *Cloth {
-	return &Cloth{AttributeBase{Name: "cloth"}, slot}
+	return &Cloth{AttributeBase{Name: "cloth"}, ClothSlot(slot)}
 }
 
 // Market attribute
 // OpCode: 34
 type Market struct {
 	AttributeBase
-	Category         uint16 `xml:"category,attr"`
-	TradeAs          uint16 `xml:"tradeAs,attr"`
-	ShowAs           uint16 `xml:"showAs,attr"`
-	ItemName         string `xml:"itemName,attr"`
-	RestrictVocation uint16 `xml:"restrictVocation,attr"`
-	RequiredLevel    uint16 `xml:"requiredLevel,attr"`
+	Category         MarketCategory `xml:"category,attr" json:"category"`
+	TradeAs          uint16         `xml:"tradeAs,attr" json:"tradeAs"`
+	ShowAs           uint16         `xml:"showAs,attr" json:"showAs"`
+	ItemName         string         `xml:"itemName,attr" json:"itemName"`
+	RestrictVocation Vocation       `xml:"restrictVocation,attr" json:"restrictVocation"`
+	RequiredLevel    uint16         `xml:"requiredLevel,attr" json:"requiredLevel"`
 }
 
 // attrName implements Attribute interface
 func (attr Market) attrName() string { return attr.Name }
 
+// OpCode implements Attribute interface
+func (attr Market) OpCode() OpCode { return OpMarket }
+
 // NewMarket creates new Market attribute
 func NewMarket(category, tradeAs, showAs uint16, itemName string,
 	restrictVocation, requiredLevel uint16) *Market {
 	return &Market{
 		AttributeBase{Name: "market"},
-		category,
+		MarketCategory(category),
 		tradeAs,
 		showAs,
 		itemName,
-		restrictVocation,
+		Vocation(restrictVocation),
 		requiredLevel,
 	}
 }
@@ -551,12 +649,15 @@ func NewMarket(category, tradeAs, showAs uint16, itemName string,
 // OpCode: 35
 type Usable struct {
 	AttributeBase
-	Val uint16 `xml:"val,attr"`
+	Val uint16 `xml:"val,attr" json:"val"`
 }
 
 // attrName implements Attribute interface
 func (attr Usable) attrName() string { return attr.Name }
 
+// OpCode implements Attribute interface
+func (attr Usable) OpCode() OpCode { return OpUsable }
+
 // NewUsable creates new Usable attribute
 func NewUsable(val uint16) *Usable {
 	return &Usable{AttributeBase{Name: "usable"}, val}
@@ -571,6 +672,9 @@ type Wrapable struct {
 // attrName implements Attribute interface
 func (attr Wrapable) attrName() string { return attr.Name }
 
+// OpCode implements Attribute interface
+func (attr Wrapable) OpCode() OpCode { return OpWrapable }
+
 // NewWrapable creates new Wrapable attribute
 func NewWrapable() *Wrapable {
 	return &Wrapable{AttributeBase{Name: "wrapable"}}
@@ -585,6 +689,9 @@ type Unwrapable struct {
 // attrName implements Attribute interface
 func (attr Unwrapable) attrName() string { return attr.Name }
 
+// OpCode implements Attribute interface
+func (attr Unwrapable) OpCode() OpCode { return OpUnwrapable }
+
 // NewUnwrapable creates new Unwrapable attribute
 func NewUnwrapable() *Unwrapable {
 	return &Unwrapable{AttributeBase{Name: "unwrapable"}}
@@ -599,6 +706,9 @@ type TopEffect struct {
 // attrName implements Attribute interface
 func (attr TopEffect) attrName() string { return attr.Name }
 
+// OpCode implements Attribute interface
+func (attr TopEffect) OpCode() OpCode { return OpTopEffect }
+
 // NewTopEffect creates new TopEffect attribute
 func NewTopEffect() *TopEffect {
 	return &TopEffect{AttributeBase{Name: "topEffect"}}
@@ -613,6 +723,9 @@ type Opacity struct {
 // attrName implements Attribute interface
 func (attr Opacity) attrName() string { return attr.Name }
 
+// OpCode implements Attribute interface
+func (attr Opacity) OpCode() OpCode { return OpOpacity }
+
 // NewOpacity creates new Opacity attribute
 func NewOpacity() *Opacity {
 	return &Opacity{AttributeBase{Name: "opacity"}}
@@ -627,6 +740,9 @@ type NotPrewalkable struct {
 // attrName implements Attribute interface
 func (attr NotPrewalkable) attrName() string { return attr.Name }
 
+// OpCode implements Attribute interface
+func (attr NotPrewalkable) OpCode() OpCode { return OpNotPrewalkable }
+
 // NewNotPrewalkable creates new NotPrewalkable attribute
 func NewNotPrewalkable() *NotPrewalkable {
 	return &NotPrewalkable{AttributeBase{Name: "notPrewalkable"}}
@@ -641,6 +757,9 @@ type FloorChange struct {
 // attrName implements Attribute interface
 func (attr FloorChange) attrName() string { return attr.Name }
 
+// OpCode implements Attribute interface
+func (attr FloorChange) OpCode() OpCode { return OpFloorChange }
+
 // NewFloorChange creates new FloorChange attribute
 func NewFloorChange() *FloorChange {
 	return &FloorChange{AttributeBase{Name: "floorChange"}}
@@ -648,78 +767,190 @@ func NewFloorChange() *FloorChange {
 
 // Deprecated attribute
 // Opcode: 254
-// It doesn't inherit from AttributeBase not to be XML serializable
-type Deprecated struct{}
+type Deprecated struct {
+	AttributeBase
+}
 
 // attrName implements Attribute interface
-func (attr Deprecated) attrName() string { return "Deprecated<254>" }
+func (attr Deprecated) attrName() string { return attr.Name }
+
+// OpCode implements Attribute interface
+func (attr Deprecated) OpCode() OpCode { return OpDeprecated }
 
 // NewDeprecated creates new Deprecated attribute
 func NewDeprecated() *Deprecated {
-	return &Deprecated{}
+	return &Deprecated{AttributeBase{Name: "deprecated"}}
+}
+
+// cloneAttribute returns copy of attribute pointed by `attr`
+func cloneAttribute(attr Attribute) Attribute {
+	val := reflect.ValueOf(attr)
+	if val.Kind() != reflect.Ptr {
+		return attr
+	}
+	clone := reflect.New(val.Elem().Type())
+	clone.Elem().Set(val.Elem())
+	return clone.Interface().(Attribute)
+}
+
+// newAttribute creates attribute of given opcode with zero values
+func newAttribute(op OpCode) Attribute {
+	switch op {
+	case OpGround:
+		return NewGround(0)
+	case OpGroundBorder:
+		return NewGroundBorder()
+	case OpOnBottom:
+		return NewOnBottom()
+	case OpOnTop:
+		return NewOnTop()
+	case OpContainer:
+		return NewContainer()
+	case OpStackable:
+		return NewStackable()
+	case OpForceUse:
+		return NewForceUse()
+	case OpMultiUse:
+		return NewMultiUse()
+	case OpWritable:
+		return NewWritable(0)
+	case OpWritableOnce:
+		return NewWritableOnce(0)
+	case OpFluidContainer:
+		return NewFluidContainer()
+	case OpSplash:
+		return NewSplash()
+	case OpNotWalkable:
+		return NewNotWalkable()
+	case OpNotMoveable:
+		return NewNotMoveable()
+	case OpBlockProjectile:
+		return NewBlockProjectile()
+	case OpNotPathable:
+		return NewNotPathable()
+	case OpNoMoveAnimation:
+		return NewNoMoveAnimation()
+	case OpPickupable:
+		return NewPickupable()
+	case OpHangable:
+		return NewHangable()
+	case OpHookSouth:
+		return NewHookSouth()
+	case OpHookEast:
+		return NewHookEast()
+	case OpRotateable:
+		return NewRotateable()
+	case OpLight:
+		return NewLight(0, 0)
+	case OpDontHide:
+		return NewDontHide()
+	case OpTranslucent:
+		return NewTranslucent()
+	case OpDisplacement:
+		return NewDisplacement(0, 0)
+	case OpElevation:
+		return NewElevation(0)
+	case OpLyingCorpse:
+		return NewLyingCorpse()
+	case OpAnimateAlways:
+		return NewAnimateAlways()
+	case OpMinimapColor:
+		return NewMinimapColor(0)
+	case OpLensHelp:
+		return NewLensHelp(0)
+	case OpFullGround:
+		return NewFullGround()
+	case OpLook:
+		return NewLook()
+	case OpCloth:
+		return NewCloth(0)
+	case OpMarket:
+		return NewMarket(0, 0, 0, "", 0, 0)
+	case OpUsable:
+		return NewUsable(0)
+	case OpWrapable:
+		return NewWrapable()
+	case OpUnwrapable:
+		return NewUnwrapable()
+	case OpTopEffect:
+		return NewTopEffect()
+	case OpOpacity:
+		return NewOpacity()
+	case OpNotPrewalkable:
+		return NewNotPrewalkable()
+	case OpFloorChange:
+		return NewFloorChange()
+	case OpDeprecated:
+		return NewDeprecated()
+	}
+	if custom := lookupCustomAttribute(op); custom != nil {
+		return custom.newAttr()
+	}
+	return nil
 }
 
 // DeserializeAttribute reads from `datfh` and creates proper attribute based
-// on given `attrOp`
-func deserializeAttribute(attrOp uint8, datfh bin.Reader) (Attribute, error) {
+// on given `attrOp`. Opcodes neither known nor registered with
+// RegisterAttribute result in Unknown attribute, or error if `strict` is set
+func deserializeAttribute(attrOp OpCode, datfh bin.Reader, strict bool) (Attribute, error) {
 	var err error
 	var val, val2 uint16
 
 	switch attrOp {
-	case 0:
+	case OpGround:
 		if val, err = datfh.UInt16(); err != nil {
 			return nil, err
 		}
 		return NewGround(val), nil
-	case 1:
+	case OpGroundBorder:
 		return NewGroundBorder(), nil
-	case 2:
+	case OpOnBottom:
 		return NewOnBottom(), nil
-	case 3:
+	case OpOnTop:
 		return NewOnTop(), nil
-	case 4:
+	case OpContainer:
 		return NewContainer(), nil
-	case 5:
+	case OpStackable:
 		return NewStackable(), nil
-	case 6:
+	case OpForceUse:
 		return NewForceUse(), nil
-	case 7:
+	case OpMultiUse:
 		return NewMultiUse(), nil
-	case 8:
+	case OpWritable:
 		if val, err = datfh.UInt16(); err != nil {
 			return nil, err
 		}
 		return NewWritable(val), nil
-	case 9:
+	case OpWritableOnce:
 		if val, err = datfh.UInt16(); err != nil {
 			return nil, err
 		}
 		return NewWritableOnce(val), nil
-	case 10:
+	case OpFluidContainer:
 		return NewFluidContainer(), nil
-	case 11:
+	case OpSplash:
 		return NewSplash(), nil
-	case 12:
+	case OpNotWalkable:
 		return NewNotWalkable(), nil
-	case 13:
+	case OpNotMoveable:
 		return NewNotMoveable(), nil
-	case 14:
+	case OpBlockProjectile:
 		return NewBlockProjectile(), nil
-	case 15:
+	case OpNotPathable:
 		return NewNotPathable(), nil
-	case 16:
+	case OpNoMoveAnimation:
 		return NewNoMoveAnimation(), nil
-	case 17:
+	case OpPickupable:
 		return NewPickupable(), nil
-	case 18:
+	case OpHangable:
 		return NewHangable(), nil
-	case 19:
+	case OpHookSouth:
 		return NewHookSouth(), nil
-	case 20:
+	case OpHookEast:
 		return NewHookEast(), nil
-	case 21:
+	case OpRotateable:
 		return NewRotateable(), nil
-	case 22:
+	case OpLight:
 		if val, err = datfh.UInt16(); err != nil {
 			return nil, err
 		}
@@ -727,11 +958,11 @@ func deserializeAttribute(attrOp uint8, datfh bin.Reader) (Attribute, error) {
 			return nil, err
 		}
 		return NewLight(val, val2), nil
-	case 23:
+	case OpDontHide:
 		return NewDontHide(), nil
-	case 24:
+	case OpTranslucent:
 		return NewTranslucent(), nil
-	case 25:
+	case OpDisplacement:
 		if val, err = datfh.UInt16(); err != nil {
 			return nil, err
 		}
@@ -739,35 +970,35 @@ func deserializeAttribute(attrOp uint8, datfh bin.Reader) (Attribute, error) {
 			return nil, err
 		}
 		return NewDisplacement(val, val2), nil
-	case 26:
+	case OpElevation:
 		if val, err = datfh.UInt16(); err != nil {
 			return nil, err
 		}
 		return NewElevation(val), nil
-	case 27:
+	case OpLyingCorpse:
 		return NewLyingCorpse(), nil
-	case 28:
+	case OpAnimateAlways:
 		return NewAnimateAlways(), nil
-	case 29:
+	case OpMinimapColor:
 		if val, err = datfh.UInt16(); err != nil {
 			return nil, err
 		}
 		return NewMinimapColor(val), nil
-	case 30:
+	case OpLensHelp:
 		if val, err = datfh.UInt16(); err != nil {
 			return nil, err
 		}
 		return NewLensHelp(val), nil
-	case 31:
+	case OpFullGround:
 		return NewFullGround(), nil
-	case 32:
+	case OpLook:
 		return NewLook(), nil
-	case 33:
+	case OpCloth:
 		if val, err = datfh.UInt16(); err != nil {
 			return nil, err
 		}
 		return NewCloth(val), nil
-	case 34:
+	case OpMarket:
 		var category, tradeAs, showAs, restrictVocation, requiredLevel uint16
 		var itemName string
 
@@ -797,29 +1028,96 @@ func deserializeAttribute(attrOp uint8, datfh bin.Reader) (Attribute, error) {
 			restrictVocation,
 			requiredLevel,
 		), nil
-	case 35:
+	case OpUsable:
 		if val, err = datfh.UInt16(); err != nil {
 			return nil, err
 		}
 		return NewUsable(val), nil
-	case 36:
+	case OpWrapable:
 		return NewWrapable(), nil
-	case 37:
+	case OpUnwrapable:
 		return NewUnwrapable(), nil
-	case 38:
+	case OpTopEffect:
 		return NewTopEffect(), nil
-	case 100:
+	case OpOpacity:
 		return NewOpacity(), nil
-	case 101:
+	case OpNotPrewalkable:
 		return NewNotPrewalkable(), nil
-	case 252:
+	case OpFloorChange:
 		return NewFloorChange(), nil
-	case 254:
+	case OpDeprecated:
 		return NewDeprecated(), nil
-	case 255:
+	case OpEnd:
 		break
 	default:
-		return nil, fmt.Errorf("Unknown attribute opcode: %d", attrOp)
+		if custom := lookupCustomAttribute(attrOp); custom != nil {
+			return custom.decode(datfh)
+		}
+		if strict {
+			return nil, fmt.Errorf("Unknown attribute opcode: %d", attrOp)
+		}
+		return NewUnknown(attrOp), nil
 	}
 	return nil, nil
 }
+
+// serializeAttribute writes opcode of given attribute followed by its data
+func serializeAttribute(attr Attribute, datfh bin.Writer) error {
+	if err := datfh.PutUInt8(uint8(attr.OpCode())); err != nil {
+		return err
+	}
+	return serializeAttributeData(attr, datfh)
+}
+
+// serializeAttributeData writes data of given attribute following its opcode
+func serializeAttributeData(attr Attribute, datfh bin.Writer) error {
+	switch attr := attr.(type) {
+	case *Ground:
+		return datfh.PutUInt16(attr.Val)
+	case *Writable:
+		return datfh.PutUInt16(attr.TextLen)
+	case *WritableOnce:
+		return datfh.PutUInt16(attr.TextLen)
+	case *Light:
+		if err := datfh.PutUInt16(attr.Intensity); err != nil {
+			return err
+		}
+		return datfh.PutUInt16(attr.Color)
+	case *Displacement:
+		if err := datfh.PutUInt16(attr.X); err != nil {
+			return err
+		}
+		return datfh.PutUInt16(attr.Y)
+	case *Elevation:
+		return datfh.PutUInt16(attr.Val)
+	case *MinimapColor:
+		return datfh.PutUInt16(attr.Val)
+	case *LensHelp:
+		return datfh.PutUInt16(attr.Val)
+	case *Cloth:
+		return datfh.PutUInt16(uint16(attr.Slot))
+	case *Market:
+		if err := datfh.PutUInt16(uint16(attr.Category)); err != nil {
+			return err
+		}
+		if err := datfh.PutUInt16(attr.TradeAs); err != nil {
+			return err
+		}
+		if err := datfh.PutUInt16(attr.ShowAs); err != nil {
+			return err
+		}
+		if err := datfh.PutString(attr.ItemName); err != nil {
+			return err
+		}
+		if err := datfh.PutUInt16(uint16(attr.RestrictVocation)); err != nil {
+			return err
+		}
+		return datfh.PutUInt16(attr.RequiredLevel)
+	case *Usable:
+		return datfh.PutUInt16(attr.Val)
+	}
+	if custom := lookupCustomAttribute(attr.OpCode()); custom != nil {
+		return custom.encode(attr, datfh)
+	}
+	return nil
+}
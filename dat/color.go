@@ -0,0 +1,125 @@
+package dat
+
+import (
+	"encoding/xml"
+	"fmt"
+	"image/color"
+)
+
+// Client colors are indexes into 6x6x6 color cube, channel values are
+// multiples of colorStep
+const (
+	colorLevels = 6
+	colorStep   = 51
+	// ColorsCount is number of colors of client color cube
+	ColorsCount = colorLevels * colorLevels * colorLevels
+)
+
+// From8bit converts 8-bit client color, as used by MinimapColor and Light,
+// into RGB color. Indexes beyond color cube are black
+func From8bit(c uint16) color.RGBA {
+	if c >= ColorsCount {
+		return color.RGBA{0, 0, 0, 255}
+	}
+	return color.RGBA{
+		uint8(c / (colorLevels * colorLevels) % colorLevels * colorStep),
+		uint8(c / colorLevels % colorLevels * colorStep),
+		uint8(c % colorLevels * colorStep),
+		255,
+	}
+}
+
+// To8bit returns index of client color nearest to given color. Alpha channel
+// is ignored
+func To8bit(c color.Color) uint16 {
+	n := color.NRGBAModel.Convert(c).(color.NRGBA)
+	return uint16(nearestLevel(n.R)*colorLevels*colorLevels +
+		nearestLevel(n.G)*colorLevels + nearestLevel(n.B))
+}
+
+func nearestLevel(val uint8) int {
+	return (int(val) + colorStep/2) / colorStep
+}
+
+// FormatRGB formats color as #rrggbb
+func FormatRGB(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// ParseRGB parses color formatted as #rrggbb
+func ParseRGB(s string) (color.RGBA, error) {
+	c := color.RGBA{A: 255}
+	if len(s) != 7 || s[0] != '#' {
+		return c, fmt.Errorf("Invalid color %q, expected #rrggbb", s)
+	}
+	if _, err := fmt.Sscanf(s[1:], "%02x%02x%02x", &c.R, &c.G, &c.B); err != nil {
+		return c, fmt.Errorf("Invalid color %q, expected #rrggbb", s)
+	}
+	return c, nil
+}
+
+// RGB returns minimap color as RGB color
+func (attr MinimapColor) RGB() color.RGBA {
+	return From8bit(attr.Val)
+}
+
+// SetRGB sets minimap color to client color nearest to given one
+func (attr *MinimapColor) SetRGB(c color.Color) {
+	attr.Val = To8bit(c)
+}
+
+// RGB returns color of light as RGB color
+func (attr Light) RGB() color.RGBA {
+	return From8bit(attr.Color)
+}
+
+// SetRGB sets color of light to client color nearest to given one
+func (attr *Light) SetRGB(c color.Color) {
+	attr.Color = To8bit(c)
+}
+
+// coloredAttribute is implemented by attributes holding client color, they
+// might be exported with additional, human readable rgb field
+type coloredAttribute interface {
+	RGB() color.RGBA
+	SetRGB(c color.Color)
+}
+
+// applyRGB sets color of attribute parsed from rgb field. It has to be called
+// before numeric fields are decoded, so numeric color wins if both are given
+func applyRGB(attr Attribute, rgb string) error {
+	colored, ok := attr.(coloredAttribute)
+	if !ok || rgb == "" {
+		return nil
+	}
+	c, err := ParseRGB(rgb)
+	if err != nil {
+		return err
+	}
+	colored.SetRGB(c)
+	return nil
+}
+
+// rgbThing is Thing exported with rgb field of its colored attributes
+type rgbThing struct {
+	*Thing
+}
+
+// MarshalJSON implements json.Marshaler interface
+func (thing rgbThing) MarshalJSON() ([]byte, error) {
+	return thing.marshalJSON(true)
+}
+
+// MarshalXML implements xml.Marshaler interface
+func (thing rgbThing) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return thing.marshalXML(e, start, true)
+}
+
+// rgbThings wraps things to be exported with rgb fields
+func rgbThings(things []*Thing) []rgbThing {
+	wrapped := make([]rgbThing, 0, len(things))
+	for _, thing := range things {
+		wrapped = append(wrapped, rgbThing{thing})
+	}
+	return wrapped
+}
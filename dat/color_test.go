@@ -0,0 +1,67 @@
+package dat
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func TestUnmarshalRGB(t *testing.T) {
+	// #ff0000 is client color 180, #0000ff is 5
+	tests := []struct {
+		doc  string
+		want uint16
+	}{
+		{`{"name": "light", "intensity": 3, "color": 5}`, 5},
+		{`{"name": "light", "intensity": 3, "rgb": "#ff0000"}`, 180},
+		{`{"name": "light", "intensity": 3, "color": 5, "rgb": "#ff0000"}`, 5},
+	}
+	for _, test := range tests {
+		attr, err := unmarshalAttributeJSON(json.RawMessage(test.doc))
+		if err != nil {
+			t.Fatalf("%s: %v", test.doc, err)
+		}
+		if light := attr.(*Light); light.Color != test.want || light.Intensity != 3 {
+			t.Errorf("%s: got color %d intensity %d, want color %d", test.doc, light.Color, light.Intensity, test.want)
+		}
+	}
+
+	doc := `<thing id="100" type="item"><attr name="minimapColor" val="5" rgb="#ff0000"></attr></thing>`
+	thing := &Thing{}
+	if err := xml.Unmarshal([]byte(doc), thing); err != nil {
+		t.Fatal(err)
+	}
+	if got := thing.Attributes[0].(*MinimapColor).Val; got != 5 {
+		t.Errorf("XML: got minimap color %d, want 5", got)
+	}
+}
+
+func TestExportRGB(t *testing.T) {
+	datfh := newTestFile(3)
+	for _, exportRGB := range []bool{false, true} {
+		datfh.ExportRGB = exportRGB
+		buf := &bytes.Buffer{}
+		if err := datfh.WriteJSON(buf); err != nil {
+			t.Fatal(err)
+		}
+		if got := strings.Contains(buf.String(), `"rgb"`); got != exportRGB {
+			t.Errorf("ExportRGB=%v: JSON has rgb field %v", exportRGB, got)
+		}
+		if _, err := ReadJSON(buf); err != nil {
+			t.Errorf("ExportRGB=%v: %v", exportRGB, err)
+		}
+
+		doc, err := xml.Marshal(datfh)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := bytes.Contains(doc, []byte(` rgb="`)); got != exportRGB {
+			t.Errorf("ExportRGB=%v: XML has rgb attribute %v", exportRGB, got)
+		}
+		if err = xml.Unmarshal(doc, NewFile(0)); err != nil {
+			t.Errorf("ExportRGB=%v: %v", exportRGB, err)
+		}
+	}
+}
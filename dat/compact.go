@@ -0,0 +1,92 @@
+package dat
+
+import (
+	"fmt"
+	"sort"
+)
+
+// RawSpriteSource provides encoded sprite data by sprite IDs, e.g. spr.File.
+// Nil data means sprite is empty
+type RawSpriteSource interface {
+	RawSprite(id int) ([]byte, error)
+}
+
+// Compaction holds result of Compact. Sprites are raw sprites of compacted
+// .spr file, sprite at index i has ID i+1, Mapping maps old sprite IDs to new
+// ones, Removed is number of dropped sprites
+type Compaction struct {
+	Sprites [][]byte
+	Mapping map[uint32]uint32
+	Removed int
+}
+
+// Compact removes sprites not referenced by any thing of file, duplicates of
+// other sprites and empty sprites, which are replaced by sprite 0. Remaining
+// sprites keep their order and are renumbered from 1, sprite groups of file
+// are rewritten to new IDs. File isn't changed if an error occurs. The new
+// pair is written with File.Save and spr.Save of returned Sprites, e.g.
+//
+//	compaction, err := dat.Compact(datfh, sprfh, sprfh.SpritesCount)
+//	...
+//	err = datfh.Save("Tibia.dat")
+//	...
+//	err = spr.Save("Tibia.spr", sprfh.Signature, features, compaction.Sprites)
+func Compact(datfh *File, sprites RawSpriteSource, spritesCount uint32) (*Compaction, error) {
+	referenced := make(map[uint32]bool)
+	for _, typ := range typeNames {
+		for _, thing := range datfh.Category(typ) {
+			for _, sprGr := range thing.SpriteGroups {
+				for _, sprID := range sprGr.Sprites {
+					if sprID > spritesCount {
+						return nil, fmt.Errorf("%s %d references sprite %d, file has %d sprites",
+							thing.Type, thing.ID, sprID, spritesCount)
+					}
+					if sprID != 0 {
+						referenced[sprID] = true
+					}
+				}
+			}
+		}
+	}
+	oldIDs := make([]uint32, 0, len(referenced))
+	for sprID := range referenced {
+		oldIDs = append(oldIDs, sprID)
+	}
+	sort.Slice(oldIDs, func(i, j int) bool { return oldIDs[i] < oldIDs[j] })
+
+	compaction := &Compaction{
+		Sprites: make([][]byte, 0, len(oldIDs)),
+		Mapping: map[uint32]uint32{0: 0},
+	}
+	// new IDs of already written sprites by their data
+	written := make(map[string]uint32)
+	for _, oldID := range oldIDs {
+		data, err := sprites.RawSprite(int(oldID))
+		if err != nil {
+			return nil, err
+		}
+		if len(data) == 0 {
+			compaction.Mapping[oldID] = 0
+			continue
+		}
+		newID, ok := written[string(data)]
+		if !ok {
+			compaction.Sprites = append(compaction.Sprites, data)
+			newID = uint32(len(compaction.Sprites))
+			written[string(data)] = newID
+		}
+		compaction.Mapping[oldID] = newID
+	}
+	compaction.Removed = int(spritesCount) - len(compaction.Sprites)
+
+	for _, typ := range typeNames {
+		for _, thing := range datfh.Category(typ) {
+			for _, sprGr := range thing.SpriteGroups {
+				for i, sprID := range sprGr.Sprites {
+					sprGr.Sprites[i] = compaction.Mapping[sprID]
+				}
+			}
+		}
+	}
+	return compaction, nil
+}
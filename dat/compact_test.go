@@ -0,0 +1,97 @@
+package dat
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/go-otserv/encoding/spr"
+)
+
+// rawSprites is RawSpriteSource of sprites kept in memory, sprite at index i
+// has ID i+1
+type rawSprites [][]byte
+
+func (sprites rawSprites) RawSprite(id int) ([]byte, error) {
+	if id < 1 || id > len(sprites) {
+		return nil, fmt.Errorf("Sprite %d out of range", id)
+	}
+	return sprites[id-1], nil
+}
+
+func TestCompact(t *testing.T) {
+	a, b, c := []byte{1, 0, 1, 0, 1, 2, 3}, []byte{0, 0, 1, 0, 4, 5, 6}, []byte{2, 0, 1, 0, 7, 8, 9}
+	// 1 and 7 are unused, 3 duplicates 2, 4 is empty
+	sprites := rawSprites{c, a, append([]byte{}, a...), nil, b, c, b}
+
+	datfh := NewFile(0)
+	item := NewThing(100, ITEM)
+	item.SpriteGroups = []*SpriteGroup{{Width: 1, Height: 1, Layers: 1, PatternXNum: 4,
+		PatternYNum: 1, PatternZNum: 1, Sprites: []uint32{2, 3, 4, 0}}}
+	effect := NewThing(1, EFFECT)
+	effect.SpriteGroups = []*SpriteGroup{{Width: 1, Height: 1, Layers: 1, PatternXNum: 2,
+		PatternYNum: 1, PatternZNum: 1, Sprites: []uint32{5, 6}}}
+	datfh.AppendThing(item)
+	datfh.AppendThing(effect)
+
+	compaction, err := Compact(datfh, sprites, uint32(len(sprites)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := [][]byte{a, b, c}; !reflect.DeepEqual(compaction.Sprites, want) {
+		t.Errorf("got sprites %v, want %v", compaction.Sprites, want)
+	}
+	wantMapping := map[uint32]uint32{0: 0, 2: 1, 3: 1, 4: 0, 5: 2, 6: 3}
+	if !reflect.DeepEqual(compaction.Mapping, wantMapping) {
+		t.Errorf("got mapping %v, want %v", compaction.Mapping, wantMapping)
+	}
+	if compaction.Removed != 4 {
+		t.Errorf("Removed = %d, want 4", compaction.Removed)
+	}
+	if got := item.SpriteGroups[0].Sprites; !reflect.DeepEqual(got, []uint32{1, 1, 0, 0}) {
+		t.Errorf("item sprites %v, want [1 1 0 0]", got)
+	}
+	if got := effect.SpriteGroups[0].Sprites; !reflect.DeepEqual(got, []uint32{2, 3}) {
+		t.Errorf("effect sprites %v, want [2 3]", got)
+	}
+
+	// compacted sprites read back from written .spr file
+	features := spr.Features{Extended: true}
+	path := filepath.Join(t.TempDir(), "Tibia.spr")
+	if err = spr.Save(path, 0x1234, features, compaction.Sprites); err != nil {
+		t.Fatal(err)
+	}
+	sprfh, err := spr.OpenWithFeatures(path, features)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sprfh.Close()
+	if sprfh.Signature != 0x1234 || sprfh.SpritesCount != 3 {
+		t.Errorf("written file has signature %X and %d sprites", sprfh.Signature, sprfh.SpritesCount)
+	}
+	for i, want := range compaction.Sprites {
+		if got, err := sprfh.RawSprite(i + 1); err != nil || !bytes.Equal(got, want) {
+			t.Errorf("sprite %d is %v, %v, want %v", i+1, got, err, want)
+		}
+	}
+	if usage, err := AnalyzeSprites(datfh, sprfh, sprfh.SpritesCount); err != nil ||
+		len(usage.Unused) != 0 || len(usage.Missing) != 0 {
+		t.Errorf("compacted file has unused or missing sprites: %+v, %v", usage, err)
+	}
+}
+
+func TestCompactOutOfRange(t *testing.T) {
+	datfh := NewFile(0)
+	item := NewThing(100, ITEM)
+	item.SpriteGroups = []*SpriteGroup{{Sprites: []uint32{1, 3}}}
+	datfh.AppendThing(item)
+
+	if _, err := Compact(datfh, rawSprites{{1}, {2}}, 2); err == nil {
+		t.Fatal("Compact succeeded with sprite out of range")
+	}
+	if got := item.SpriteGroups[0].Sprites; !reflect.DeepEqual(got, []uint32{1, 3}) {
+		t.Errorf("failed Compact changed sprites to %v", got)
+	}
+}
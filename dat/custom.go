@@ -0,0 +1,117 @@
+package dat
+
+import (
+	"fmt"
+	"sync"
+
+	bin "github.com/go-otserv/encoding/binary"
+)
+
+// AttributeDecoder reads data of custom attribute, its opcode is already
+// consumed
+type AttributeDecoder func(datfh bin.Reader) (Attribute, error)
+
+// AttributeEncoder writes data of custom attribute, its opcode is already
+// written
+type AttributeEncoder func(attr Attribute, datfh bin.Writer) error
+
+// customAttribute holds everything needed to handle registered attribute
+type customAttribute struct {
+	name    string
+	newAttr func() Attribute
+	decode  AttributeDecoder
+	encode  AttributeEncoder
+}
+
+var (
+	customMu         sync.RWMutex
+	customAttributes = map[OpCode]*customAttribute{}
+)
+
+// RegisterAttribute registers custom attribute of given opcode. Name is used
+// in XML and JSON exports, newAttr creates zero valued attribute restored from
+// those exports, decode and encode handle its data in .dat file.
+// Custom attribute types have to embed AttributeBase and return registered
+// opcode from their OpCode method.
+// Opcodes and names of built-in attributes can't be registered.
+func RegisterAttribute(op OpCode, name string, newAttr func() Attribute,
+	decode AttributeDecoder, encode AttributeEncoder) error {
+	customMu.Lock()
+	defer customMu.Unlock()
+
+	if _, ok := opNames[op]; ok {
+		return fmt.Errorf("Opcode %d is already used by %s attribute", op, op)
+	}
+	if _, ok := customAttributes[op]; ok {
+		return fmt.Errorf("Opcode %d is already registered", op)
+	}
+	if name == unknownName {
+		return fmt.Errorf("Attribute name %q is already used", name)
+	}
+	for _, used := range opNames {
+		if used == name {
+			return fmt.Errorf("Attribute name %q is already used", name)
+		}
+	}
+	for _, custom := range customAttributes {
+		if custom.name == name {
+			return fmt.Errorf("Attribute name %q is already used", name)
+		}
+	}
+	customAttributes[op] = &customAttribute{name, newAttr, decode, encode}
+	return nil
+}
+
+func lookupCustomAttribute(op OpCode) *customAttribute {
+	customMu.RLock()
+	defer customMu.RUnlock()
+	return customAttributes[op]
+}
+
+func customOpCodeByName(name string) (OpCode, bool) {
+	customMu.RLock()
+	defer customMu.RUnlock()
+	for op, custom := range customAttributes {
+		if custom.name == name {
+			return op, true
+		}
+	}
+	return 0, false
+}
+
+const unknownName = "unknown"
+
+// Unknown attribute holds opcode not known to this package nor registered
+// with RegisterAttribute. Only attributes without data can be read that way,
+// see File.Strict
+type Unknown struct {
+	AttributeBase
+	Op OpCode `xml:"opcode,attr" json:"opcode"`
+}
+
+// attrName implements Attribute interface
+func (attr Unknown) attrName() string { return attr.Name }
+
+// OpCode implements Attribute interface
+func (attr Unknown) OpCode() OpCode { return attr.Op }
+
+// NewUnknown creates new Unknown attribute
+func NewUnknown(op OpCode) *Unknown {
+	return &Unknown{AttributeBase{Name: unknownName}, op}
+}
+
+// newAttributeByName creates attribute of given name with zero values
+func newAttributeByName(name string) (Attribute, error) {
+	if name == unknownName {
+		return NewUnknown(0), nil
+	}
+	op, ok := opCodeByName(name)
+	if !ok {
+		return nil, fmt.Errorf("Unknown attribute: %q", name)
+	}
+	attr := newAttribute(op)
+	if attr == nil {
+		return nil, fmt.Errorf("Attribute %q can't be assigned to thing", name)
+	}
+	return attr, nil
+}
@@ -0,0 +1,159 @@
+package dat
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"reflect"
+	"sync"
+	"testing"
+
+	bin "github.com/go-otserv/encoding/binary"
+)
+
+const opTestWeight OpCode = 110
+
+// testWeight is custom attribute registered by registerTestWeight
+type testWeight struct {
+	AttributeBase
+	Val uint16 `xml:"val,attr" json:"val"`
+}
+
+// OpCode implements Attribute interface
+func (attr testWeight) OpCode() OpCode { return opTestWeight }
+
+func newTestWeight(val uint16) *testWeight {
+	return &testWeight{AttributeBase{Name: "testWeight"}, val}
+}
+
+var registerOnce sync.Once
+
+// registerTestWeight registers testWeight attribute, registry is global so
+// it is registered once for all tests
+func registerTestWeight(tb testing.TB) {
+	var err error
+	registerOnce.Do(func() {
+		err = RegisterAttribute(opTestWeight, "testWeight",
+			func() Attribute { return newTestWeight(0) },
+			func(datfh bin.Reader) (Attribute, error) {
+				val, err := datfh.UInt16()
+				if err != nil {
+					return nil, err
+				}
+				return newTestWeight(val), nil
+			},
+			func(attr Attribute, datfh bin.Writer) error {
+				return datfh.PutUInt16(attr.(*testWeight).Val)
+			})
+	})
+	if err != nil {
+		tb.Fatal(err)
+	}
+}
+
+// testFileWith returns test file with attribute added to its first item
+func testFileWith(attr Attribute) *File {
+	datfh := newTestFile(3)
+	datfh.Items[0].Attributes = append(datfh.Items[0].Attributes, attr)
+	return datfh
+}
+
+func TestUnknownAttribute(t *testing.T) {
+	data := encodeTestFile(t, testFileWith(NewUnknown(120)))
+	datfh, err := openSequential(writeTestFile(t, data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Attribute{NewGround(150), NewMinimapColor(12), NewUnknown(120)}
+	if got := datfh.Items[0].Attributes; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected attributes %v, got %v", want, got)
+	}
+	if got := encodeTestFile(t, datfh); !bytes.Equal(got, data) {
+		t.Error("binary of unknown attribute round trip differs")
+	}
+	if name := OpCode(120).String(); name != "OpCode<120>" {
+		t.Errorf("expected name OpCode<120>, got %s", name)
+	}
+
+	datfh, err = Open(writeTestFile(t, data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	datfh.Strict = true
+	if err = datfh.Deserialize(); err == nil {
+		t.Error("expected strict deserialization to fail on unknown opcode")
+	}
+}
+
+func TestRegisterAttribute(t *testing.T) {
+	registerTestWeight(t)
+
+	data := encodeTestFile(t, testFileWith(newTestWeight(1234)))
+	datfh, err := Open(writeTestFile(t, data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	datfh.Strict = true
+	if err = datfh.Deserialize(); err != nil {
+		t.Fatal(err)
+	}
+	item := datfh.Items[0]
+	if attr, ok := Get[*testWeight](item); !ok || attr.Val != 1234 {
+		t.Errorf("expected testWeight 1234, got %v", item.Attributes)
+	}
+	if got := encodeTestFile(t, datfh); !bytes.Equal(got, data) {
+		t.Error("binary of custom attribute round trip differs")
+	}
+	if name := opTestWeight.String(); name != "testWeight" {
+		t.Errorf("expected name testWeight, got %s", name)
+	}
+
+	jsonData, err := json.Marshal(item)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromJSON := &Thing{}
+	if err = json.Unmarshal(jsonData, fromJSON); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(fromJSON.Attributes, item.Attributes) {
+		t.Errorf("expected JSON attributes %v, got %v", item.Attributes, fromJSON.Attributes)
+	}
+
+	xmlData, err := xml.Marshal(item)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromXML := &Thing{}
+	if err = xml.Unmarshal(xmlData, fromXML); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(fromXML.Attributes, item.Attributes) {
+		t.Errorf("expected XML attributes %v, got %v", item.Attributes, fromXML.Attributes)
+	}
+}
+
+func TestRegisterAttributeErrors(t *testing.T) {
+	registerTestWeight(t)
+
+	newAttr := func() Attribute { return newTestWeight(0) }
+	tests := []struct {
+		name string
+		op   OpCode
+		attr string
+	}{
+		{"built-in opcode", OpLight, "testBrightness"},
+		{"registered opcode", opTestWeight, "testMass"},
+		{"unknown name", 111, unknownName},
+		{"built-in name", 111, "light"},
+		{"registered name", 111, "testWeight"},
+	}
+	for _, tt := range tests {
+		if err := RegisterAttribute(tt.op, tt.attr, newAttr, nil, nil); err == nil {
+			t.Errorf("%s: expected registration to fail", tt.name)
+		}
+	}
+	if custom := lookupCustomAttribute(111); custom != nil {
+		t.Errorf("expected opcode 111 not to be registered, got %s", custom.name)
+	}
+}
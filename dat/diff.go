@@ -0,0 +1,348 @@
+package dat
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// Kinds of changes reported by Diff
+const (
+	Added   = "added"
+	Removed = "removed"
+	Changed = "changed"
+)
+
+// diffedSpriteGroupFields lists sprite group fields compared by Diff, in
+// order of reporting
+var diffedSpriteGroupFields = []string{
+	"frameGroupType", "width", "height", "realSize", "layers", "patternX",
+	"patternY", "patternZ", "async", "startPhase", "loopCount",
+}
+
+// FileDiff holds differences between two files, see Diff
+type FileDiff struct {
+	Categories []*CategoryDiff `json:"categories"`
+}
+
+// CategoryDiff holds differences between things of single type
+type CategoryDiff struct {
+	Category string       `json:"category"`
+	Added    []uint16     `json:"added"`
+	Removed  []uint16     `json:"removed"`
+	Changed  []*ThingDiff `json:"changed"`
+}
+
+// ThingDiff holds differences between two versions of thing of the same ID
+type ThingDiff struct {
+	ID           uint16               `json:"id"`
+	Type         string               `json:"type"`
+	Attributes   []*AttributeChange   `json:"attributes,omitempty"`
+	SpriteGroups []*SpriteGroupChange `json:"spriteGroups,omitempty"`
+	Old          *Thing               `json:"-"`
+	New          *Thing               `json:"-"`
+}
+
+// AttributeChange describes attribute added to, removed from or changed in
+// thing. Old is nil for added attribute, New is nil for removed one
+type AttributeChange struct {
+	OpCode OpCode         `json:"opcode"`
+	Name   string         `json:"name"`
+	Kind   string         `json:"kind"`
+	Fields []*FieldChange `json:"fields,omitempty"`
+	Old    Attribute      `json:"-"`
+	New    Attribute      `json:"-"`
+}
+
+// SpriteGroupChange describes sprite group added to, removed from or changed
+// in thing. Group is 1-based position of sprite group in thing
+type SpriteGroupChange struct {
+	Group   int             `json:"group"`
+	Kind    string          `json:"kind"`
+	Fields  []*FieldChange  `json:"fields,omitempty"`
+	Sprites []*SpriteChange `json:"sprites,omitempty"`
+	Old     *SpriteGroup    `json:"-"`
+	New     *SpriteGroup    `json:"-"`
+}
+
+// FieldChange describes changed value of single field
+type FieldChange struct {
+	Name string `json:"name"`
+	Old  string `json:"old"`
+	New  string `json:"new"`
+}
+
+// SpriteChange describes changed sprite ID at given index of
+// SpriteGroup.Sprites
+type SpriteChange struct {
+	Index int    `json:"index"`
+	Old   uint32 `json:"old"`
+	New   uint32 `json:"new"`
+}
+
+// Diff compares two files. Things are matched by category and ID, every
+// category is reported even if there are no differences
+func Diff(a, b *File) *FileDiff {
+	diff := &FileDiff{Categories: make([]*CategoryDiff, 0, len(typeNames))}
+	for _, typ := range typeNames {
+		diff.Categories = append(diff.Categories,
+			diffCategory(typ, a.Category(typ), b.Category(typ)))
+	}
+	return diff
+}
+
+// Empty reports whether there are no differences at all
+func (diff *FileDiff) Empty() bool {
+	for _, cat := range diff.Categories {
+		if len(cat.Added) > 0 || len(cat.Removed) > 0 || len(cat.Changed) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Category returns differences of things of given type or nil
+func (diff *FileDiff) Category(typ string) *CategoryDiff {
+	for _, cat := range diff.Categories {
+		if cat.Category == typ {
+			return cat
+		}
+	}
+	return nil
+}
+
+func diffCategory(typ string, a, b []*Thing) *CategoryDiff {
+	cat := &CategoryDiff{
+		Category: typ,
+		Added:    make([]uint16, 0),
+		Removed:  make([]uint16, 0),
+		Changed:  make([]*ThingDiff, 0),
+	}
+	byID := make(map[uint16]*Thing, len(b))
+	for _, thing := range b {
+		byID[thing.ID] = thing
+	}
+	seen := make(map[uint16]bool, len(a))
+	for _, before := range a {
+		seen[before.ID] = true
+		after, ok := byID[before.ID]
+		if !ok {
+			cat.Removed = append(cat.Removed, before.ID)
+			continue
+		}
+		if thingDiff := DiffThings(before, after); thingDiff != nil {
+			cat.Changed = append(cat.Changed, thingDiff)
+		}
+	}
+	for _, after := range b {
+		if !seen[after.ID] {
+			cat.Added = append(cat.Added, after.ID)
+		}
+	}
+	return cat
+}
+
+// DiffThings compares attributes and sprite groups of two things, it returns
+// nil if they don't differ. Attributes are matched by opcode, sprite groups
+// by position
+func DiffThings(before, after *Thing) *ThingDiff {
+	thingDiff := &ThingDiff{ID: after.ID, Type: after.Type, Old: before, New: after}
+
+	for _, oldAttr := range before.Attributes {
+		newAttr := after.Attribute(oldAttr.OpCode())
+		if newAttr == nil {
+			thingDiff.Attributes = append(thingDiff.Attributes,
+				newAttributeChange(Removed, oldAttr, nil))
+			continue
+		}
+		if fields := diffAttributes(oldAttr, newAttr); len(fields) > 0 {
+			change := newAttributeChange(Changed, oldAttr, newAttr)
+			change.Fields = fields
+			thingDiff.Attributes = append(thingDiff.Attributes, change)
+		}
+	}
+	for _, newAttr := range after.Attributes {
+		if !before.Has(newAttr.OpCode()) {
+			thingDiff.Attributes = append(thingDiff.Attributes,
+				newAttributeChange(Added, nil, newAttr))
+		}
+	}
+
+	for i := 0; i < len(before.SpriteGroups) || i < len(after.SpriteGroups); i++ {
+		var change *SpriteGroupChange
+		switch {
+		case i >= len(after.SpriteGroups):
+			change = &SpriteGroupChange{Kind: Removed, Old: before.SpriteGroups[i]}
+		case i >= len(before.SpriteGroups):
+			change = &SpriteGroupChange{Kind: Added, New: after.SpriteGroups[i]}
+		default:
+			change = diffSpriteGroups(before.SpriteGroups[i], after.SpriteGroups[i])
+		}
+		if change != nil {
+			change.Group = i + 1
+			thingDiff.SpriteGroups = append(thingDiff.SpriteGroups, change)
+		}
+	}
+
+	if len(thingDiff.Attributes) == 0 && len(thingDiff.SpriteGroups) == 0 {
+		return nil
+	}
+	return thingDiff
+}
+
+func newAttributeChange(kind string, before, after Attribute) *AttributeChange {
+	attr := after
+	if attr == nil {
+		attr = before
+	}
+	return &AttributeChange{
+		OpCode: attr.OpCode(),
+		Name:   attr.OpCode().String(),
+		Kind:   kind,
+		Old:    before,
+		New:    after,
+	}
+}
+
+func diffAttributes(before, after Attribute) []*FieldChange {
+	oldFields := attributeFields(before)
+	newFields := attributeFields(after)
+	if reflect.TypeOf(before) != reflect.TypeOf(after) || len(oldFields) != len(newFields) {
+		return []*FieldChange{{"type", reflect.TypeOf(before).String(), reflect.TypeOf(after).String()}}
+	}
+	fields := make([]*FieldChange, 0)
+	for i, oldField := range oldFields {
+		oldVal := fmt.Sprint(oldField.value.Interface())
+		newVal := fmt.Sprint(newFields[i].value.Interface())
+		if oldVal != newVal {
+			fields = append(fields, &FieldChange{oldField.name, oldVal, newVal})
+		}
+	}
+	return fields
+}
+
+func diffSpriteGroups(before, after *SpriteGroup) *SpriteGroupChange {
+	change := &SpriteGroupChange{Kind: Changed, Old: before, New: after}
+
+	oldVal := reflect.ValueOf(before).Elem()
+	newVal := reflect.ValueOf(after).Elem()
+	for _, name := range diffedSpriteGroupFields {
+		structField := spriteGroupFields[name]
+		change.addField(name, oldVal.FieldByName(structField).Interface(),
+			newVal.FieldByName(structField).Interface())
+	}
+
+	change.addField("phases", before.phasesCount(), after.phasesCount())
+	for i := 0; i < len(before.AnimationPhases) && i < len(after.AnimationPhases); i++ {
+		oldPhase, newPhase := before.AnimationPhases[i], after.AnimationPhases[i]
+		change.addField(fmt.Sprintf("phase[%d].frameA", i), oldPhase.FrameA, newPhase.FrameA)
+		change.addField(fmt.Sprintf("phase[%d].frameB", i), oldPhase.FrameB, newPhase.FrameB)
+	}
+
+	change.addField("spritesCount", len(before.Sprites), len(after.Sprites))
+	for i := 0; i < len(before.Sprites) && i < len(after.Sprites); i++ {
+		if before.Sprites[i] != after.Sprites[i] {
+			change.Sprites = append(change.Sprites,
+				&SpriteChange{i, before.Sprites[i], after.Sprites[i]})
+		}
+	}
+
+	if len(change.Fields) == 0 && len(change.Sprites) == 0 {
+		return nil
+	}
+	return change
+}
+
+func (change *SpriteGroupChange) addField(name string, before, after interface{}) {
+	oldVal, newVal := fmt.Sprint(before), fmt.Sprint(after)
+	if oldVal != newVal {
+		change.Fields = append(change.Fields, &FieldChange{name, oldVal, newVal})
+	}
+}
+
+// WriteJSON writes differences as JSON document
+func (diff *FileDiff) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(diff)
+}
+
+// WriteText writes differences in human readable form, one line per added,
+// removed or changed thing, attribute and sprite group
+func (diff *FileDiff) WriteText(w io.Writer) error {
+	for _, cat := range diff.Categories {
+		_, err := fmt.Fprintf(w, "%s: %d added, %d removed, %d changed\n",
+			cat.Category, len(cat.Added), len(cat.Removed), len(cat.Changed))
+		if err != nil {
+			return err
+		}
+		for _, id := range cat.Added {
+			if _, err = fmt.Fprintf(w, "+ %s %d\n", cat.Category, id); err != nil {
+				return err
+			}
+		}
+		for _, id := range cat.Removed {
+			if _, err = fmt.Fprintf(w, "- %s %d\n", cat.Category, id); err != nil {
+				return err
+			}
+		}
+		for _, thingDiff := range cat.Changed {
+			if err = thingDiff.writeText(w); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (thingDiff *ThingDiff) writeText(w io.Writer) error {
+	if _, err := fmt.Fprintf(w, "~ %s %d\n", thingDiff.Type, thingDiff.ID); err != nil {
+		return err
+	}
+	for _, change := range thingDiff.Attributes {
+		line := fmt.Sprintf("    %s attr %s", kindSymbol(change.Kind), change.Name)
+		if len(change.Fields) > 0 {
+			line += ": " + formatFieldChanges(change.Fields)
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	for _, change := range thingDiff.SpriteGroups {
+		line := fmt.Sprintf("    %s sprite group %d", kindSymbol(change.Kind), change.Group)
+		details := make([]string, 0, 2)
+		if len(change.Fields) > 0 {
+			details = append(details, formatFieldChanges(change.Fields))
+		}
+		if len(change.Sprites) > 0 {
+			details = append(details, fmt.Sprintf("%d sprite IDs changed", len(change.Sprites)))
+		}
+		if len(details) > 0 {
+			line += ": " + strings.Join(details, "; ")
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func kindSymbol(kind string) string {
+	switch kind {
+	case Added:
+		return "+"
+	case Removed:
+		return "-"
+	}
+	return "~"
+}
+
+func formatFieldChanges(fields []*FieldChange) string {
+	parts := make([]string, 0, len(fields))
+	for _, field := range fields {
+		parts = append(parts, fmt.Sprintf("%s %q -> %q", field.Name, field.Old, field.New))
+	}
+	return strings.Join(parts, ", ")
+}
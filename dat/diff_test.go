@@ -0,0 +1,169 @@
+package dat
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// diffTestFiles returns two versions of file: item 100 is changed, item
+// 101 unchanged, item 102 removed and effect 2 added
+func diffTestFiles() (*File, *File) {
+	before, after := NewFile(0), NewFile(0)
+	sprGr := func(w uint8, sprites ...uint32) *SpriteGroup {
+		return &SpriteGroup{Width: w, Height: 1, Layers: 1, PatternXNum: 1, PatternYNum: 1,
+			PatternZNum: 1, Sprites: sprites}
+	}
+
+	item := NewThing(100, ITEM)
+	// attributes are paired by opcode regardless of their order
+	item.Attributes = []Attribute{NewLight(3, 5), NewPickupable(), NewGround(100)}
+	item.SpriteGroups = []*SpriteGroup{sprGr(1, 1), sprGr(1, 2), sprGr(1, 3)}
+	before.AppendThing(item)
+
+	item = NewThing(100, ITEM)
+	item.Attributes = []Attribute{NewGround(150), NewLight(3, 6), NewStackable()}
+	// sprite groups are paired by position: first is unchanged, second
+	// changed and third removed
+	item.SpriteGroups = []*SpriteGroup{sprGr(1, 1), sprGr(2, 2, 7)}
+	after.AppendThing(item)
+
+	for _, datfh := range []*File{before, after} {
+		unchanged := NewThing(101, ITEM)
+		unchanged.Attributes = []Attribute{NewPickupable()}
+		unchanged.SpriteGroups = []*SpriteGroup{sprGr(1, 4)}
+		datfh.AppendThing(unchanged)
+		datfh.AppendThing(NewThing(1, EFFECT))
+	}
+	before.AppendThing(NewThing(102, ITEM))
+	after.AppendThing(NewThing(2, EFFECT))
+	return before, after
+}
+
+func TestDiff(t *testing.T) {
+	before, after := diffTestFiles()
+	diff := Diff(before, after)
+	items := diff.Category(ITEM)
+	if !reflect.DeepEqual(items.Added, []uint16{}) || !reflect.DeepEqual(items.Removed, []uint16{102}) {
+		t.Errorf("items added %v, removed %v", items.Added, items.Removed)
+	}
+	if effects := diff.Category(EFFECT); !reflect.DeepEqual(effects.Added, []uint16{2}) ||
+		len(effects.Changed) != 0 {
+		t.Errorf("effects added %v, changed %d", effects.Added, len(effects.Changed))
+	}
+	if len(items.Changed) != 1 {
+		t.Fatalf("got %d changed items, want 1", len(items.Changed))
+	}
+
+	thingDiff := items.Changed[0]
+	type attrChange struct {
+		name, kind string
+		fields     []FieldChange
+	}
+	gotAttrs := make([]attrChange, 0)
+	for _, change := range thingDiff.Attributes {
+		fields := make([]FieldChange, 0)
+		for _, field := range change.Fields {
+			fields = append(fields, *field)
+		}
+		gotAttrs = append(gotAttrs, attrChange{change.Name, change.Kind, fields})
+	}
+	wantAttrs := []attrChange{
+		{"light", Changed, []FieldChange{{"color", "5", "6"}}},
+		{"pickupable", Removed, []FieldChange{}},
+		{"ground", Changed, []FieldChange{{"val", "100", "150"}}},
+		{"stackable", Added, []FieldChange{}},
+	}
+	if !reflect.DeepEqual(gotAttrs, wantAttrs) {
+		t.Errorf("got attribute changes %+v, want %+v", gotAttrs, wantAttrs)
+	}
+
+	if len(thingDiff.SpriteGroups) != 2 {
+		t.Fatalf("got %d sprite group changes, want 2", len(thingDiff.SpriteGroups))
+	}
+	changed, removed := thingDiff.SpriteGroups[0], thingDiff.SpriteGroups[1]
+	if changed.Group != 2 || changed.Kind != Changed || removed.Group != 3 || removed.Kind != Removed {
+		t.Errorf("got sprite group changes %+v and %+v", changed, removed)
+	}
+	wantFields := []*FieldChange{{"width", "1", "2"}, {"spritesCount", "1", "2"}}
+	if !reflect.DeepEqual(changed.Fields, wantFields) {
+		t.Errorf("got sprite group fields %v, want %v", changed.Fields, wantFields)
+	}
+	if len(changed.Sprites) != 0 {
+		t.Errorf("got sprite changes %v, want none", changed.Sprites)
+	}
+
+	if !Diff(after, after).Empty() || diff.Empty() {
+		t.Error("Empty reports wrong result")
+	}
+	if DiffThings(after.Items[0], after.Items[0]) != nil {
+		t.Error("DiffThings of the same thing isn't nil")
+	}
+}
+
+func TestDiffOutput(t *testing.T) {
+	before, after := diffTestFiles()
+	// keep sprite IDs changed, but not their count
+	after.Items[0].SpriteGroups[1].Sprites = []uint32{8}
+	diff := Diff(before, after)
+
+	buf := &bytes.Buffer{}
+	if err := diff.WriteText(buf); err != nil {
+		t.Fatal(err)
+	}
+	want := `item: 0 added, 1 removed, 1 changed
+- item 102
+~ item 100
+    ~ attr light: color "5" -> "6"
+    - attr pickupable
+    ~ attr ground: val "100" -> "150"
+    + attr stackable
+    ~ sprite group 2: width "1" -> "2"; 1 sprite IDs changed
+    - sprite group 3
+outfit: 0 added, 0 removed, 0 changed
+effect: 1 added, 0 removed, 0 changed
++ effect 2
+missile: 0 added, 0 removed, 0 changed
+`
+	if buf.String() != want {
+		t.Errorf("got text\n%s\nwant\n%s", buf, want)
+	}
+
+	buf.Reset()
+	if err := diff.WriteJSON(buf); err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Categories []struct {
+			Category string
+			Added    []uint16
+			Removed  []uint16
+			Changed  []struct {
+				ID           uint16
+				Attributes   []struct{ Name, Kind string }
+				SpriteGroups []struct {
+					Group   int
+					Kind    string
+					Sprites []SpriteChange
+				}
+			}
+		}
+	}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	items := doc.Categories[0]
+	if items.Category != ITEM || !reflect.DeepEqual(items.Removed, []uint16{102}) ||
+		len(items.Changed) != 1 || items.Changed[0].ID != 100 ||
+		len(items.Changed[0].Attributes) != 4 || items.Changed[0].Attributes[3].Kind != Added {
+		t.Errorf("unexpected JSON document:\n%s", buf)
+	}
+	sprGrs := items.Changed[0].SpriteGroups
+	if len(sprGrs) != 2 || !reflect.DeepEqual(sprGrs[0].Sprites, []SpriteChange{{0, 2, 8}}) {
+		t.Errorf("unexpected sprite group changes in JSON:\n%s", buf)
+	}
+	if effects := doc.Categories[2]; !reflect.DeepEqual(effects.Added, []uint16{2}) {
+		t.Errorf("effects added %v, want [2]", effects.Added)
+	}
+}
@@ -0,0 +1,136 @@
+package dat
+
+import "fmt"
+
+// maxThingID is the highest ID header of .dat file can describe
+const maxThingID = 0xFFFF
+
+// Thing returns thing of given type and client ID or nil if there is no such
+// thing
+func (datfh *File) Thing(typ string, id uint16) *Thing {
+	things := datfh.Category(typ)
+	first, _ := datfh.idRange(typ)
+	// things are stored in ID order without gaps
+	if idx := int(id) - first; idx >= 0 && idx < len(things) && things[idx].ID == id {
+		return things[idx]
+	}
+	return findThing(things, id)
+}
+
+// NextID returns ID of thing which would be added to category of given type
+func (datfh *File) NextID(typ string) uint16 {
+	return nextThingID(typ, datfh.Category(typ))
+}
+
+// AddThing appends new thing without attributes to category of given type.
+// Thing has single sprite group of one blank sprite
+func (datfh *File) AddThing(typ string) (*Thing, error) {
+	if err := datfh.checkEditable(typ); err != nil {
+		return nil, err
+	}
+	id, err := datfh.freeID(typ)
+	if err != nil {
+		return nil, err
+	}
+	thing := NewThing(id, typ)
+	thing.SpriteGroups = []*SpriteGroup{NewSpriteGroup()}
+	datfh.AppendThing(thing)
+	return thing, nil
+}
+
+// CloneThing appends deep copy of thing of given type and ID to the end of
+// its category, under the next free ID
+func (datfh *File) CloneThing(typ string, id uint16) (*Thing, error) {
+	if err := datfh.checkEditable(typ); err != nil {
+		return nil, err
+	}
+	thing := datfh.Thing(typ, id)
+	if thing == nil {
+		return nil, fmt.Errorf("There is no %s %d", typ, id)
+	}
+	nextID, err := datfh.freeID(typ)
+	if err != nil {
+		return nil, err
+	}
+	clone := thing.Clone()
+	clone.ID = nextID
+	datfh.AppendThing(clone)
+	return clone, nil
+}
+
+// RemoveLastThing removes the last thing of category of given type and
+// returns it. Things in the middle of category can't be removed, since the
+// client identifies things by their position
+func (datfh *File) RemoveLastThing(typ string) (*Thing, error) {
+	if err := datfh.checkEditable(typ); err != nil {
+		return nil, err
+	}
+	things := datfh.Category(typ)
+	if len(things) == 0 {
+		return nil, fmt.Errorf("There are no things of type %s", typ)
+	}
+	last := things[len(things)-1]
+	datfh.setCategory(typ, things[:len(things)-1])
+	datfh.resetCounts()
+	return last, nil
+}
+
+// ReplaceThing replaces thing of the same type and ID as given one. It
+// returns the replaced thing
+func (datfh *File) ReplaceThing(thing *Thing) (*Thing, error) {
+	if err := datfh.checkEditable(thing.Type); err != nil {
+		return nil, err
+	}
+	things := datfh.Category(thing.Type)
+	for i, current := range things {
+		if current.ID == thing.ID {
+			things[i] = thing
+			return current, nil
+		}
+	}
+	return nil, fmt.Errorf("There is no %s %d", thing.Type, thing.ID)
+}
+
+// SetAttribute sets attribute of thing of given type and ID, replacing
+// attribute of the same opcode
+func (datfh *File) SetAttribute(typ string, id uint16, attr Attribute) error {
+	thing := datfh.Thing(typ, id)
+	if thing == nil {
+		return fmt.Errorf("There is no %s %d", typ, id)
+	}
+	thing.SetAttribute(attr)
+	return nil
+}
+
+// UnsetAttribute removes attributes of given opcode from thing of given type
+// and ID. It reports whether any attribute was removed
+func (datfh *File) UnsetAttribute(typ string, id uint16, op OpCode) (bool, error) {
+	thing := datfh.Thing(typ, id)
+	if thing == nil {
+		return false, fmt.Errorf("There is no %s %d", typ, id)
+	}
+	return thing.UnsetAttribute(op), nil
+}
+
+// checkEditable returns error if things of given type can't be added or
+// removed: unknown type or file with things left to deserialize
+func (datfh *File) checkEditable(typ string) error {
+	switch typ {
+	case ITEM, OUTFIT, EFFECT, MISSILE:
+	default:
+		return fmt.Errorf("Unknown thing type %q", typ)
+	}
+	if datfh.next.done < datfh.Total() {
+		return fmt.Errorf("File is not fully deserialized")
+	}
+	return nil
+}
+
+// freeID returns ID for thing appended to category of given type
+func (datfh *File) freeID(typ string) (uint16, error) {
+	things := datfh.Category(typ)
+	if len(things) > 0 && things[len(things)-1].ID == maxThingID {
+		return 0, fmt.Errorf("There is no free ID of type %s", typ)
+	}
+	return nextThingID(typ, things), nil
+}
@@ -0,0 +1,122 @@
+package dat
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEditThings(t *testing.T) {
+	datfh := newTestFile(3)
+	total := datfh.Total()
+
+	added, err := datfh.AddThing(ITEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if added.ID != 103 || datfh.Thing(ITEM, 103) != added || len(added.SpriteGroups) != 1 {
+		t.Errorf("added item %d with %d sprite groups", added.ID, len(added.SpriteGroups))
+	}
+	clone, err := datfh.CloneThing(ITEM, 101)
+	if err != nil {
+		t.Fatal(err)
+	}
+	original := datfh.Thing(ITEM, 101)
+	if clone.ID != 104 || !reflect.DeepEqual(clone.Attributes, original.Attributes) ||
+		!reflect.DeepEqual(clone.SpriteGroups, original.SpriteGroups) {
+		t.Errorf("clone has ID %d", clone.ID)
+	}
+	if clone.Attributes[0] == datfh.Items[1].Attributes[0] {
+		t.Error("clone shares attributes with original")
+	}
+	if datfh.NextID(ITEM) != 105 || datfh.NextID(OUTFIT) != 2 {
+		t.Errorf("next IDs are %d and %d", datfh.NextID(ITEM), datfh.NextID(OUTFIT))
+	}
+
+	if err = datfh.SetAttribute(ITEM, 104, NewLight(3, 5)); err != nil {
+		t.Fatal(err)
+	}
+	if removed, err := datfh.UnsetAttribute(ITEM, 104, OpStackable); err != nil || !removed {
+		t.Errorf("UnsetAttribute = %v, %v", removed, err)
+	}
+	if datfh.Items[1].Attribute(OpLight).(*Light).Intensity != 4 || !datfh.Items[1].Has(OpStackable) {
+		t.Error("editing clone changed original")
+	}
+
+	replacement := NewThing(1, EFFECT)
+	replacement.SpriteGroups = []*SpriteGroup{NewSpriteGroup()}
+	replaced, err := datfh.ReplaceThing(replacement)
+	if err != nil || replaced.ID != 1 || datfh.Thing(EFFECT, 1) != replacement {
+		t.Errorf("ReplaceThing = %v, %v", replaced, err)
+	}
+
+	last, err := datfh.RemoveLastThing(OUTFIT)
+	if err != nil || last.ID != 1 || len(datfh.Outfits) != 0 {
+		t.Errorf("RemoveLastThing = %v, %v", last, err)
+	}
+	if datfh.Total() != total+1 {
+		t.Errorf("Total() = %d, want %d", datfh.Total(), total+1)
+	}
+
+	// serialized file describes edited things
+	reopened, err := openSequential(writeTestFile(t, encodeTestFile(t, datfh)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, typ := range typeNames {
+		if len(reopened.Category(typ)) != len(datfh.Category(typ)) {
+			t.Errorf("reopened file has %d things of type %s, want %d",
+				len(reopened.Category(typ)), typ, len(datfh.Category(typ)))
+		}
+	}
+	if DiffThings(reopened.Thing(ITEM, 104), datfh.Thing(ITEM, 104)) != nil {
+		t.Error("edited clone differs after serialization")
+	}
+}
+
+func TestAppendThingCounts(t *testing.T) {
+	datfh := NewFile(0)
+	datfh.AppendThing(NewThing(100, ITEM))
+	datfh.AppendThing(NewThing(1, MISSILE))
+	if datfh.Total() != 2 || datfh.NextID(ITEM) != 101 {
+		t.Errorf("Total() = %d after appending 2 things", datfh.Total())
+	}
+}
+
+func TestEditErrors(t *testing.T) {
+	datfh := newTestFile(1)
+	if _, err := datfh.AddThing("creature"); err == nil {
+		t.Error("AddThing of unknown type succeeded")
+	}
+	if _, err := datfh.CloneThing(ITEM, 150); err == nil {
+		t.Error("CloneThing of missing item succeeded")
+	}
+	if _, err := datfh.ReplaceThing(NewThing(150, ITEM)); err == nil {
+		t.Error("ReplaceThing of missing item succeeded")
+	}
+	if err := datfh.SetAttribute(EFFECT, 2, NewTopEffect()); err == nil {
+		t.Error("SetAttribute of missing effect succeeded")
+	}
+	if _, err := datfh.RemoveLastThing(OUTFIT); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := datfh.RemoveLastThing(OUTFIT); err == nil {
+		t.Error("RemoveLastThing of empty category succeeded")
+	}
+
+	datfh.Items[0].ID = maxThingID
+	if _, err := datfh.AddThing(ITEM); err == nil {
+		t.Error("AddThing after item 0xFFFF succeeded")
+	}
+
+	// things can't be added while file is being deserialized
+	opened, err := Open(writeTestFile(t, encodeTestFile(t, newTestFile(3))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = opened.DeserializeStep(2, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = opened.AddThing(ITEM); err == nil {
+		t.Error("AddThing to partially deserialized file succeeded")
+	}
+}
@@ -0,0 +1,273 @@
+package dat
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ClothSlot is equipment slot of Cloth attribute
+type ClothSlot uint16
+
+// Equipment slots
+const (
+	SlotBothHands ClothSlot = iota
+	SlotHead
+	SlotNecklace
+	SlotBackpack
+	SlotArmor
+	SlotShield
+	SlotWeapon
+	SlotLegs
+	SlotFeet
+	SlotRing
+	SlotAmmo
+	SlotStoreInbox
+)
+
+var clothSlotNames = map[ClothSlot]string{
+	SlotBothHands:  "bothHands",
+	SlotHead:       "head",
+	SlotNecklace:   "necklace",
+	SlotBackpack:   "backpack",
+	SlotArmor:      "armor",
+	SlotShield:     "shield",
+	SlotWeapon:     "weapon",
+	SlotLegs:       "legs",
+	SlotFeet:       "feet",
+	SlotRing:       "ring",
+	SlotAmmo:       "ammo",
+	SlotStoreInbox: "storeInbox",
+}
+
+// String returns name of slot, or its number if slot is unknown
+func (slot ClothSlot) String() string {
+	if name, ok := clothSlotNames[slot]; ok {
+		return name
+	}
+	return strconv.Itoa(int(slot))
+}
+
+// MarshalText implements encoding.TextMarshaler interface
+func (slot ClothSlot) MarshalText() ([]byte, error) {
+	return []byte(slot.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler interface, it accepts
+// names and numbers
+func (slot *ClothSlot) UnmarshalText(text []byte) error {
+	val, err := parseEnum(string(text), clothSlotNames)
+	*slot = val
+	return err
+}
+
+// UnmarshalJSON implements json.Unmarshaler interface, it accepts names and
+// numbers
+func (slot *ClothSlot) UnmarshalJSON(data []byte) error {
+	return unmarshalEnumJSON(data, slot)
+}
+
+// MarketCategory is category of item in market
+type MarketCategory uint16
+
+// Market categories
+const (
+	MarketArmors MarketCategory = iota + 1
+	MarketAmulets
+	MarketBoots
+	MarketContainers
+	MarketDecoration
+	MarketFood
+	MarketHelmetsHats
+	MarketLegs
+	MarketOthers
+	MarketPotions
+	MarketRings
+	MarketRunes
+	MarketShields
+	MarketTools
+	MarketValuables
+	MarketAmmunition
+	MarketAxes
+	MarketClubs
+	MarketDistanceWeapons
+	MarketSwords
+	MarketWandsRods
+	MarketPremiumScrolls
+	MarketTibiaCoins
+	MarketMetaWeapons MarketCategory = 255
+)
+
+var marketCategoryNames = map[MarketCategory]string{
+	MarketArmors:          "armors",
+	MarketAmulets:         "amulets",
+	MarketBoots:           "boots",
+	MarketContainers:      "containers",
+	MarketDecoration:      "decoration",
+	MarketFood:            "food",
+	MarketHelmetsHats:     "helmetsHats",
+	MarketLegs:            "legs",
+	MarketOthers:          "others",
+	MarketPotions:         "potions",
+	MarketRings:           "rings",
+	MarketRunes:           "runes",
+	MarketShields:         "shields",
+	MarketTools:           "tools",
+	MarketValuables:       "valuables",
+	MarketAmmunition:      "ammunition",
+	MarketAxes:            "axes",
+	MarketClubs:           "clubs",
+	MarketDistanceWeapons: "distanceWeapons",
+	MarketSwords:          "swords",
+	MarketWandsRods:       "wandsRods",
+	MarketPremiumScrolls:  "premiumScrolls",
+	MarketTibiaCoins:      "tibiaCoins",
+	MarketMetaWeapons:     "metaWeapons",
+}
+
+// String returns name of category, or its number if category is unknown
+func (category MarketCategory) String() string {
+	if name, ok := marketCategoryNames[category]; ok {
+		return name
+	}
+	return strconv.Itoa(int(category))
+}
+
+// MarshalText implements encoding.TextMarshaler interface
+func (category MarketCategory) MarshalText() ([]byte, error) {
+	return []byte(category.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler interface, it accepts
+// names and numbers
+func (category *MarketCategory) UnmarshalText(text []byte) error {
+	val, err := parseEnum(string(text), marketCategoryNames)
+	*category = val
+	return err
+}
+
+// UnmarshalJSON implements json.Unmarshaler interface, it accepts names and
+// numbers
+func (category *MarketCategory) UnmarshalJSON(data []byte) error {
+	return unmarshalEnumJSON(data, category)
+}
+
+// Vocation is bitmask of vocations allowed to use item, zero means no
+// restriction
+type Vocation uint16
+
+// Vocations, VocationPromoted restricts item to promoted characters of
+// other set vocations
+const (
+	VocationKnight Vocation = 1 << iota
+	VocationPaladin
+	VocationSorcerer
+	VocationDruid
+	VocationPromoted
+	VocationNone Vocation = 0
+)
+
+var vocationNames = map[Vocation]string{
+	VocationKnight:   "knight",
+	VocationPaladin:  "paladin",
+	VocationSorcerer: "sorcerer",
+	VocationDruid:    "druid",
+	VocationPromoted: "promoted",
+}
+
+// Has reports whether all vocations of given mask are set
+func (voc Vocation) Has(mask Vocation) bool {
+	return voc&mask == mask
+}
+
+// Set adds vocations of given mask
+func (voc *Vocation) Set(mask Vocation) {
+	*voc |= mask
+}
+
+// Clear removes vocations of given mask
+func (voc *Vocation) Clear(mask Vocation) {
+	*voc &^= mask
+}
+
+// String returns comma separated names of set vocations, e.g.
+// "knight,promoted", unknown bits are appended as number. Zero is "none"
+func (voc Vocation) String() string {
+	if voc == VocationNone {
+		return "none"
+	}
+	names := make([]string, 0, len(vocationNames))
+	rest := voc
+	for bit := VocationKnight; bit <= VocationPromoted; bit <<= 1 {
+		if voc.Has(bit) {
+			names = append(names, vocationNames[bit])
+			rest.Clear(bit)
+		}
+	}
+	if rest != 0 {
+		names = append(names, strconv.Itoa(int(rest)))
+	}
+	return strings.Join(names, ",")
+}
+
+// MarshalText implements encoding.TextMarshaler interface
+func (voc Vocation) MarshalText() ([]byte, error) {
+	return []byte(voc.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler interface, it accepts
+// comma separated names and numbers
+func (voc *Vocation) UnmarshalText(text []byte) error {
+	*voc = VocationNone
+	if string(text) == "none" {
+		return nil
+	}
+	for _, part := range strings.Split(string(text), ",") {
+		bits, err := parseEnum(strings.TrimSpace(part), vocationNames)
+		if err != nil {
+			return err
+		}
+		voc.Set(bits)
+	}
+	return nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler interface, it accepts names and
+// numbers
+func (voc *Vocation) UnmarshalJSON(data []byte) error {
+	return unmarshalEnumJSON(data, voc)
+}
+
+// parseEnum returns value of given name or number
+func parseEnum[T ~uint16](text string, names map[T]string) (T, error) {
+	for val, name := range names {
+		if name == text {
+			return val, nil
+		}
+	}
+	num, err := strconv.ParseUint(text, 0, 16)
+	if err != nil {
+		return 0, fmt.Errorf("Unknown %T %q", T(0), text)
+	}
+	return T(num), nil
+}
+
+// unmarshalEnumJSON decodes JSON number or string of name into enum
+func unmarshalEnumJSON[T ~uint16](data []byte, val *T) error {
+	var num uint16
+	if err := json.Unmarshal(data, &num); err == nil {
+		*val = T(num)
+		return nil
+	}
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return err
+	}
+	unmarshaler, ok := any(val).(encoding.TextUnmarshaler)
+	if !ok {
+		return fmt.Errorf("Invalid %T value %s", *val, data)
+	}
+	return unmarshaler.UnmarshalText([]byte(text))
+}
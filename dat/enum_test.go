@@ -0,0 +1,206 @@
+package dat
+
+import (
+	"encoding"
+	"encoding/json"
+	"encoding/xml"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type enumCase[T ~uint16] struct {
+	val  T
+	text string
+}
+
+// testEnum checks that enum values are formatted as given text and parsed
+// back from it, from JSON strings of it and from JSON numbers
+func testEnum[T ~uint16, P interface {
+	*T
+	encoding.TextUnmarshaler
+	json.Unmarshaler
+}](t *testing.T, cases []enumCase[T]) {
+	t.Helper()
+	for _, tc := range cases {
+		if got := any(tc.val).(interface{ String() string }).String(); got != tc.text {
+			t.Errorf("expected %d formatted as %q, got %q", tc.val, tc.text, got)
+		}
+		var parsed T
+		if err := P(&parsed).UnmarshalText([]byte(tc.text)); err != nil || parsed != tc.val {
+			t.Errorf("expected %q parsed as %d, got %d (%v)", tc.text, tc.val, parsed, err)
+		}
+
+		data, err := json.Marshal(tc.val)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := `"` + tc.text + `"`; string(data) != want {
+			t.Errorf("expected JSON %s, got %s", want, data)
+		}
+		var fromJSON, fromNumber T
+		if err = json.Unmarshal(data, P(&fromJSON)); err != nil || fromJSON != tc.val {
+			t.Errorf("expected JSON %s decoded as %d, got %d (%v)", data, tc.val, fromJSON, err)
+		}
+		number, _ := json.Marshal(uint16(tc.val))
+		if err = json.Unmarshal(number, P(&fromNumber)); err != nil || fromNumber != tc.val {
+			t.Errorf("expected JSON %s decoded as %d, got %d (%v)", number, tc.val, fromNumber, err)
+		}
+	}
+}
+
+func TestClothSlot(t *testing.T) {
+	testEnum(t, []enumCase[ClothSlot]{
+		{SlotBothHands, "bothHands"},
+		{SlotHead, "head"},
+		{SlotStoreInbox, "storeInbox"},
+		{42, "42"},
+	})
+
+	var slot ClothSlot
+	if err := slot.UnmarshalText([]byte("0x4")); err != nil || slot != SlotArmor {
+		t.Errorf("expected armor, got %v (%v)", slot, err)
+	}
+	if err := slot.UnmarshalText([]byte("hand")); err == nil {
+		t.Error("expected error parsing unknown slot name")
+	}
+	if err := json.Unmarshal([]byte("true"), &slot); err == nil {
+		t.Error("expected error decoding JSON boolean")
+	}
+}
+
+func TestMarketCategory(t *testing.T) {
+	testEnum(t, []enumCase[MarketCategory]{
+		{MarketArmors, "armors"},
+		{MarketHelmetsHats, "helmetsHats"},
+		{MarketTibiaCoins, "tibiaCoins"},
+		{MarketMetaWeapons, "metaWeapons"},
+		{0, "0"},
+		{100, "100"},
+	})
+
+	var category MarketCategory
+	if err := category.UnmarshalText([]byte("70000")); err == nil {
+		t.Error("expected error parsing category out of range")
+	}
+	if err := json.Unmarshal([]byte(`"hats"`), &category); err == nil {
+		t.Error("expected error decoding unknown category name")
+	}
+}
+
+func TestVocation(t *testing.T) {
+	testEnum(t, []enumCase[Vocation]{
+		{VocationNone, "none"},
+		{VocationKnight, "knight"},
+		{VocationKnight | VocationPromoted, "knight,promoted"},
+		{VocationSorcerer | VocationDruid, "sorcerer,druid"},
+		{VocationPaladin | 64, "paladin,64"},
+		{1024, "1024"},
+	})
+
+	var voc Vocation
+	if err := voc.UnmarshalText([]byte("promoted, knight")); err != nil ||
+		voc != VocationKnight|VocationPromoted || !voc.Has(VocationKnight) || voc.Has(VocationDruid) {
+		t.Errorf("expected promoted knight, got %v (%v)", voc, err)
+	}
+	voc.Clear(VocationPromoted)
+	voc.Set(VocationDruid)
+	if voc != VocationKnight|VocationDruid {
+		t.Errorf("expected knight and druid, got %v", voc)
+	}
+	if err := voc.UnmarshalText([]byte("knight,wizard")); err == nil {
+		t.Error("expected error parsing unknown vocation name")
+	}
+}
+
+func TestEnumAttributes(t *testing.T) {
+	item := NewThing(100, ITEM)
+	item.Attributes = []Attribute{NewCloth(uint16(SlotHead)), NewMarket(uint16(MarketHelmetsHats),
+		100, 100, "helmet", uint16(VocationKnight|VocationPromoted), 20)}
+
+	data, err := json.Marshal(item)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`"slot":"head"`, `"category":"helmetsHats"`,
+		`"restrictVocation":"knight,promoted"`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("expected JSON to contain %s, got %s", want, data)
+		}
+	}
+	fromJSON := &Thing{}
+	if err = json.Unmarshal(data, fromJSON); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(fromJSON.Attributes, item.Attributes) {
+		t.Errorf("expected JSON attributes %v, got %v", item.Attributes, fromJSON.Attributes)
+	}
+
+	if data, err = xml.Marshal(item); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`slot="head"`, `category="helmetsHats"`,
+		`restrictVocation="knight,promoted"`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("expected XML to contain %s, got %s", want, data)
+		}
+	}
+	fromXML := &Thing{}
+	if err = xml.Unmarshal(data, fromXML); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(fromXML.Attributes, item.Attributes) {
+		t.Errorf("expected XML attributes %v, got %v", item.Attributes, fromXML.Attributes)
+	}
+
+	// numbers are accepted in place of names
+	doc := `<thing id="100" type="item"><attr name="cloth" slot="1"></attr></thing>`
+	fromXML = &Thing{}
+	if err = xml.Unmarshal([]byte(doc), fromXML); err != nil {
+		t.Fatal(err)
+	}
+	if cloth, ok := Get[*Cloth](fromXML); !ok || cloth.Slot != SlotHead {
+		t.Errorf("expected cloth of head slot, got %v", fromXML.Attributes)
+	}
+}
+
+func TestQueryEnumNames(t *testing.T) {
+	datfh := &File{}
+	things := []*Thing{
+		{ID: 100, Type: ITEM, Attributes: []Attribute{NewCloth(uint16(SlotHead)),
+			NewMarket(uint16(MarketHelmetsHats), 100, 100, "helmet", uint16(VocationKnight|VocationPromoted), 20)}},
+		{ID: 101, Type: ITEM, Attributes: []Attribute{NewCloth(uint16(SlotArmor)),
+			NewMarket(uint16(MarketArmors), 101, 101, "armor", 0, 0)}},
+		{ID: 102, Type: ITEM, Attributes: []Attribute{NewCloth(42)}},
+	}
+	for _, thing := range things {
+		datfh.AppendThing(thing)
+	}
+
+	tests := []struct {
+		expr string
+		want []string
+	}{
+		{"market.category == helmetsHats", []string{"item 100"}},
+		{"market.category == 7", []string{"item 100"}},
+		{"market.category < helmetsHats", []string{"item 101"}},
+		{"cloth.slot == head", []string{"item 100"}},
+		{"cloth.slot != head", []string{"item 101", "item 102"}},
+		{"cloth.slot == 42", []string{"item 102"}},
+		{"cloth.slot == head || cloth.slot == armor", []string{"item 100", "item 101"}},
+		{`market.restrictVocation == "knight,promoted"`, []string{"item 100"}},
+		{"market.restrictVocation == knight,promoted", []string{"item 100"}},
+		{"market.restrictVocation == none", []string{"item 101"}},
+		{"market.category == hats", []string{}},
+	}
+	for _, tt := range tests {
+		things, err := datfh.Query(tt.expr)
+		if err != nil {
+			t.Errorf("%s: %v", tt.expr, err)
+			continue
+		}
+		if got := thingIDs(things); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: expected %v, got %v", tt.expr, tt.want, got)
+		}
+	}
+}
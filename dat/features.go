@@ -0,0 +1,67 @@
+package dat
+
+import "time"
+
+// Features describes optional parts of .dat format, which depend on client
+// version and are usually configured by .otfi file
+type Features struct {
+	// Extended stores sprite IDs as uint32 instead of uint16
+	Extended bool
+	// FrameGroups stores outfit sprites in groups of frame group types
+	FrameGroups bool
+	// ImprovedAnimations stores async mode, start phase, loop count and
+	// durations of animation phases
+	ImprovedAnimations bool
+}
+
+// DefaultFeatures are features of recent clients, used unless File has
+// Features set
+var DefaultFeatures = Features{Extended: true, FrameGroups: true, ImprovedAnimations: true}
+
+// codec holds settings of encoding things of single file
+type codec struct {
+	features Features
+	profile  *Profile
+	strict   bool
+}
+
+// defaultCodec encodes things with DefaultFeatures and opcodes of recent
+// clients
+var defaultCodec = codec{features: DefaultFeatures}
+
+// codec returns settings used to encode things of file
+func (datfh *File) codec() codec {
+	c := codec{features: DefaultFeatures, profile: datfh.Profile, strict: datfh.Strict}
+	if datfh.Features != nil {
+		c.features = *datfh.Features
+	}
+	return c
+}
+
+// decodeOpCode converts opcode read from file into opcode of recent clients
+func (c codec) decodeOpCode(raw uint8) OpCode {
+	if c.profile == nil {
+		return OpCode(raw)
+	}
+	return c.profile.decodeOpCode(raw)
+}
+
+// encodeOpCode converts opcode into one written to file, second returned
+// value is false if attribute doesn't exist in file format
+func (c codec) encodeOpCode(op OpCode) (uint8, bool) {
+	if c.profile == nil {
+		return uint8(op), true
+	}
+	return c.profile.encodeOpCode(op)
+}
+
+// defaultPhaseDurations sets durations of animation phases unknown without
+// improved animations to ones the client uses for legacy animations
+func defaultPhaseDurations(typ string, sprGr *SpriteGroup) {
+	for _, animPhase := range sprGr.AnimationPhases {
+		if animPhase.FrameA == 0 && animPhase.FrameB == 0 {
+			duration := uint32(legacyPhaseDuration(typ) / time.Millisecond)
+			animPhase.FrameA, animPhase.FrameB = duration, duration
+		}
+	}
+}
@@ -1,6 +1,9 @@
 package dat
 
 import (
+	"fmt"
+	"os"
+
 	bin "github.com/go-otserv/encoding/binary"
 )
 
@@ -13,21 +16,61 @@ type File struct {
 	Outfits         []*Thing
 	Effects         []*Thing
 	Missiles        []*Thing
-	itemsCount      int
-	outfitsCount    int
-	effectsCount    int
-	missilesCount   int
+	// Strict makes deserialization fail on unknown attribute opcodes instead
+	// of keeping them as Unknown attributes
+	Strict bool
+	// Features of format used to read and write things, DefaultFeatures if
+	// nil
+	Features *Features
+	// Profile maps attribute opcodes of file to opcodes of recent clients,
+	// opcodes are not mapped if nil
+	Profile *Profile
+	// ExportRGB adds human readable rgb field to colored attributes written
+	// by WriteJSON and MarshalXML
+	ExportRGB     bool
+	next          cursor
+	itemsCount    int
+	outfitsCount  int
+	effectsCount  int
+	missilesCount int
+}
+
+// NewFile creates new empty File, not backed by any file on disk
+func NewFile(signature uint32) *File {
+	datfh := &File{Signature: signature, ContentRevision: uint16(signature)}
+	datfh.Items = make([]*Thing, 0)
+	datfh.Outfits = make([]*Thing, 0)
+	datfh.Effects = make([]*Thing, 0)
+	datfh.Missiles = make([]*Thing, 0)
+	datfh.resetCounts()
+	return datfh
 }
 
-// Open opens given file for reading
+// Open opens given file of DefaultFeatures for reading
 func Open(path string) (*File, error) {
+	return OpenWithFeatures(path, DefaultFeatures)
+}
+
+// OpenWithProfile opens given file of client version described by given
+// profile for reading
+func OpenWithProfile(path string, profile *Profile) (*File, error) {
+	datfh, err := OpenWithFeatures(path, profile.Features)
+	if datfh != nil {
+		datfh.Profile = profile
+	}
+	return datfh, err
+}
+
+// OpenWithFeatures opens given file of given features for reading, see
+// otfi package for reading features from .otfi file
+func OpenWithFeatures(path string, features Features) (*File, error) {
 	var itemsCount, outfitsCount, effectsCount, missilesCount uint16
 
 	buffh, err := bin.OpenBufferedFile(path)
 	if err != nil {
 		return nil, err
 	}
-	datfh := &File{*buffh, 0, 0, nil, nil, nil, nil, 0, 0, 0, 0}
+	datfh := &File{BufferedFile: *buffh, Features: &features}
 
 	if datfh.Signature, err = datfh.UInt32(); err != nil {
 		return datfh, err
@@ -45,10 +88,10 @@ func Open(path string) (*File, error) {
 	if missilesCount, err = datfh.UInt16(); err != nil {
 		return datfh, err
 	}
-	datfh.itemsCount = int(itemsCount + 1)
-	datfh.outfitsCount = int(outfitsCount + 1)
-	datfh.effectsCount = int(effectsCount + 1)
-	datfh.missilesCount = int(missilesCount + 1)
+	datfh.itemsCount = int(itemsCount) + 1
+	datfh.outfitsCount = int(outfitsCount) + 1
+	datfh.effectsCount = int(effectsCount) + 1
+	datfh.missilesCount = int(missilesCount) + 1
 
 	datfh.Items = make([]*Thing, 0, itemsCount+1)
 	datfh.Outfits = make([]*Thing, 0, outfitsCount+1)
@@ -60,27 +103,7 @@ func Open(path string) (*File, error) {
 
 // Deserialize parses .dat file to extract things information
 func (datfh *File) Deserialize() error {
-	prChan := make(chan int)
-	errChan := make(chan error)
-	doneChan := make(chan bool)
-	defer close(prChan)
-	defer close(errChan)
-	defer close(doneChan)
-
-	go datfh.DeserializeWithProgress(prChan, errChan, doneChan)
-	consumeProgress := func() error {
-		for {
-			select {
-			case <-prChan:
-				break
-			case err := <-errChan:
-				return err
-			case <-doneChan:
-				return nil
-			}
-		}
-	}
-	return consumeProgress()
+	return datfh.DeserializeWith(nil)
 }
 
 // DeserializeWithProgress does the same thing as Deserialize additionally
@@ -88,56 +111,38 @@ func (datfh *File) Deserialize() error {
 // written to doneChan
 // As File is not thread safe, don't ever run this method in multiple
 // gorutines
+//
+// Deprecated: channels have to be serviced by caller until done or error is
+// received, use DeserializeWith instead
 func (datfh *File) DeserializeWithProgress(prChan chan<- int, errChan chan<- error, doneChan chan<- bool) {
-	var err error
-	var thing *Thing
-
-	typeNames := []string{ITEM, OUTFIT, EFFECT, MISSILE}
-	typeCount := map[string]int{
-		ITEM:    datfh.itemsCount,
-		OUTFIT:  datfh.outfitsCount,
-		EFFECT:  datfh.effectsCount,
-		MISSILE: datfh.missilesCount,
-	}
-	typeToFirstID := map[string]int{
-		ITEM:    100,
-		OUTFIT:  1,
-		EFFECT:  1,
-		MISSILE: 1,
-	}
-	allCount := datfh.itemsCount + datfh.outfitsCount + datfh.effectsCount +
-		datfh.missilesCount
-
-	progress := func(n, total float64) int {
-		return int(n / total * 100)
-	}
-
-	currentProgress := -1
 	previousProgress := -1
-	var commonID uint16 = 99
-	for _, typ := range typeNames {
-		firstID := typeToFirstID[typ]
-		for itemCid := firstID; itemCid < typeCount[typ]; itemCid++ {
-			commonID++
-			if thing, err = DeserializeThing(commonID, typ, datfh); err != nil {
-				errChan <- err
-				return
-			}
-			datfh.AppendThing(thing)
-
-			currentProgress = progress(float64(commonID), float64(allCount))
-			if currentProgress != previousProgress {
-				previousProgress = currentProgress
-				prChan <- currentProgress
-			}
+	err := datfh.DeserializeWith(ProgressFunc(func(done, total int) {
+		if currentProgress := done * 100 / total; currentProgress != previousProgress {
+			previousProgress = currentProgress
+			prChan <- currentProgress
 		}
+	}))
+	if err != nil {
+		errChan <- err
+		return
 	}
 	doneChan <- true
 }
 
 // AppendThing appends given thing to proper list of (items|outfits|missiles|
-// effects) depending of thing Type
+// effects) depending of thing Type. Header counts of fully deserialized file
+// are updated, so Total includes appended thing
 func (datfh *File) AppendThing(thing *Thing) {
+	complete := datfh.next.done >= datfh.Total()
+	datfh.appendThing(thing)
+	if complete {
+		datfh.resetCounts()
+	}
+}
+
+// appendThing appends thing to list of its type, leaving header counts
+// unchanged
+func (datfh *File) appendThing(thing *Thing) {
 	switch thing.Type {
 	case ITEM:
 		datfh.Items = append(datfh.Items, thing)
@@ -149,3 +154,91 @@ func (datfh *File) AppendThing(thing *Thing) {
 		datfh.Missiles = append(datfh.Missiles, thing)
 	}
 }
+
+// Category returns list of things of given type (item, outfit, effect,
+// missile)
+func (datfh *File) Category(typ string) []*Thing {
+	switch typ {
+	case ITEM:
+		return datfh.Items
+	case OUTFIT:
+		return datfh.Outfits
+	case EFFECT:
+		return datfh.Effects
+	case MISSILE:
+		return datfh.Missiles
+	}
+	return nil
+}
+
+// setCategory replaces list of things of given type
+func (datfh *File) setCategory(typ string, things []*Thing) {
+	switch typ {
+	case ITEM:
+		datfh.Items = things
+	case OUTFIT:
+		datfh.Outfits = things
+	case EFFECT:
+		datfh.Effects = things
+	case MISSILE:
+		datfh.Missiles = things
+	}
+}
+
+// resetCounts sets things counts to match lists of things, so there is
+// nothing left to deserialize
+func (datfh *File) resetCounts() {
+	datfh.itemsCount = len(datfh.Items) + 100
+	datfh.outfitsCount = len(datfh.Outfits) + 1
+	datfh.effectsCount = len(datfh.Effects) + 1
+	datfh.missilesCount = len(datfh.Missiles) + 1
+	datfh.next = cursor{category: len(typeNames), done: datfh.Total()}
+}
+
+// Serialize writes header and all things in .dat format. Things counts in
+// header are derived from lengths of Items, Outfits, Effects and Missiles
+func (datfh *File) Serialize(w bin.Writer) error {
+	if err := w.PutUInt32(datfh.Signature); err != nil {
+		return err
+	}
+	categories := [][]*Thing{datfh.Items, datfh.Outfits, datfh.Effects, datfh.Missiles}
+	for typ, things := range categories {
+		maxID := len(things)
+		if typ == 0 {
+			maxID += 99
+		}
+		if maxID > maxThingID {
+			return fmt.Errorf("Too many things of type %s: %d", typeNames[typ], len(things))
+		}
+		if err := w.PutUInt16(uint16(maxID)); err != nil {
+			return err
+		}
+	}
+	c := datfh.codec()
+	for _, things := range categories {
+		for _, thing := range things {
+			if err := thing.serialize(w, c); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Save serializes File to .dat file at given path
+func (datfh *File) Save(path string) error {
+	fh, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	w := bin.NewBufferedWriter(fh)
+	if err = datfh.Serialize(w); err != nil {
+		fh.Close()
+		return err
+	}
+	if err = w.Flush(); err != nil {
+		fh.Close()
+		return err
+	}
+	return fh.Close()
+}
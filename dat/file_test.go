@@ -0,0 +1,111 @@
+package dat
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	bin "github.com/go-otserv/encoding/binary"
+)
+
+// newTestSpriteGroup returns sprite group of given dimensions and number of
+// animation phases, sprites have IDs counted from firstID
+func newTestSpriteGroup(w, h, layers, px, py, pz uint8, phases int, firstID uint32) *SpriteGroup {
+	sprGr := &SpriteGroup{
+		Group: 1, Width: w, Height: h, Layers: layers,
+		PatternXNum: px, PatternYNum: py, PatternZNum: pz,
+		AnimationPhases: make([]*AnimationPhase, 0),
+	}
+	if w > 1 || h > 1 {
+		sprGr.RealSize = 64
+	}
+	if phases > 1 {
+		sprGr.Async = 1
+		for i := 0; i < phases; i++ {
+			sprGr.AnimationPhases = append(sprGr.AnimationPhases, &AnimationPhase{100, 200})
+		}
+	}
+	count := int(w) * int(h) * int(layers) * int(px) * int(py) * int(pz) * phases
+	for i := 0; i < count; i++ {
+		sprGr.Sprites = append(sprGr.Sprites, firstID+uint32(i))
+	}
+	return sprGr
+}
+
+// newTestFile returns file of things of every category, having attributes
+// of all data layouts. Items are repeated to get given number of items
+func newTestFile(items int) *File {
+	datfh := NewFile(0x4A10)
+	templates := []func(item *Thing){
+		func(item *Thing) {
+			item.Attributes = []Attribute{NewGround(150), NewMinimapColor(12)}
+			item.SpriteGroups = []*SpriteGroup{newTestSpriteGroup(1, 1, 1, 4, 4, 1, 1, 1)}
+		},
+		func(item *Thing) {
+			item.Attributes = []Attribute{NewPickupable(), NewStackable(),
+				NewMarket(7, 101, 101, "gold coin", 0, 0), NewLight(4, 215), NewDeprecated()}
+			item.SpriteGroups = []*SpriteGroup{newTestSpriteGroup(1, 1, 1, 4, 2, 1, 1, 20)}
+		},
+		func(item *Thing) {
+			item.Attributes = []Attribute{NewPickupable(), NewCloth(4), NewDisplacement(8, 8)}
+			item.SpriteGroups = []*SpriteGroup{newTestSpriteGroup(2, 2, 1, 1, 1, 1, 3, 30)}
+		},
+	}
+	for i := 0; i < items; i++ {
+		item := NewThing(uint16(100+i), ITEM)
+		templates[i%len(templates)](item)
+		datfh.AppendThing(item)
+	}
+
+	outfit := NewThing(1, OUTFIT)
+	outfit.SpriteGroups = []*SpriteGroup{
+		newTestSpriteGroup(1, 1, 2, 4, 3, 2, 1, 50),
+		newTestSpriteGroup(1, 1, 2, 4, 3, 2, 2, 100),
+	}
+	outfit.SpriteGroups[1].Group = 2
+	outfit.SpriteGroups[1].FrameGroupType = FrameGroupMoving
+	effect := NewThing(1, EFFECT)
+	effect.Attributes = []Attribute{NewTopEffect()}
+	effect.SpriteGroups = []*SpriteGroup{newTestSpriteGroup(1, 1, 1, 1, 1, 1, 4, 200)}
+	missile := NewThing(1, MISSILE)
+	missile.SpriteGroups = []*SpriteGroup{newTestSpriteGroup(1, 1, 1, 3, 3, 1, 1, 70000)}
+	for _, thing := range []*Thing{outfit, effect, missile} {
+		datfh.AppendThing(thing)
+	}
+	return datfh
+}
+
+// encodeTestFile returns file serialized in .dat format
+func encodeTestFile(tb testing.TB, datfh *File) []byte {
+	buf := &bytes.Buffer{}
+	w := bin.NewBufferedWriter(buf)
+	if err := datfh.Serialize(w); err != nil {
+		tb.Fatal(err)
+	}
+	if err := w.Flush(); err != nil {
+		tb.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// writeTestFile writes data to temporary .dat file and returns its path
+func writeTestFile(tb testing.TB, data []byte) string {
+	path := filepath.Join(tb.TempDir(), "Tibia.dat")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		tb.Fatal(err)
+	}
+	return path
+}
+
+// openSequential opens and deserializes .dat file at given path
+func openSequential(path string) (*File, error) {
+	datfh, err := Open(path)
+	if err != nil {
+		return nil, err
+	}
+	if err = datfh.Deserialize(); err != nil {
+		return nil, err
+	}
+	return datfh, nil
+}
@@ -0,0 +1,181 @@
+package dat
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// JSONVersion is version of JSON schema produced by WriteJSON. ReadJSON
+// rejects documents of other versions
+const JSONVersion = 1
+
+// jsonFile is JSON representation of whole File
+type jsonFile struct {
+	Version         int      `json:"version"`
+	Signature       uint32   `json:"signature"`
+	ContentRevision uint16   `json:"contentRevision"`
+	Items           []*Thing `json:"items"`
+	Outfits         []*Thing `json:"outfits"`
+	Effects         []*Thing `json:"effects"`
+	Missiles        []*Thing `json:"missiles"`
+}
+
+// jsonThing is JSON representation of Thing. Every attribute is an object
+// with "name" of attribute and its fields, e.g.
+// {"name": "light", "intensity": 3, "color": 215}
+type jsonThing struct {
+	ID           uint16            `json:"id"`
+	Type         string            `json:"type"`
+	Attributes   []json.RawMessage `json:"attributes"`
+	SpriteGroups []*SpriteGroup    `json:"spriteGroups"`
+}
+
+// WriteJSON writes whole File: header, things of every category with their
+// attributes and sprite groups as JSON document
+func (datfh *File) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	doc := &jsonFile{
+		Version:         JSONVersion,
+		Signature:       datfh.Signature,
+		ContentRevision: datfh.ContentRevision,
+		Items:           datfh.Items,
+		Outfits:         datfh.Outfits,
+		Effects:         datfh.Effects,
+		Missiles:        datfh.Missiles,
+	}
+	if !datfh.ExportRGB {
+		return enc.Encode(doc)
+	}
+	// categories shadow those of embedded jsonFile
+	return enc.Encode(&struct {
+		*jsonFile
+		Items    []rgbThing `json:"items"`
+		Outfits  []rgbThing `json:"outfits"`
+		Effects  []rgbThing `json:"effects"`
+		Missiles []rgbThing `json:"missiles"`
+	}{doc, rgbThings(datfh.Items), rgbThings(datfh.Outfits),
+		rgbThings(datfh.Effects), rgbThings(datfh.Missiles)})
+}
+
+// ReadJSON creates new File from JSON document produced by WriteJSON
+func ReadJSON(r io.Reader) (*File, error) {
+	doc := &jsonFile{}
+	if err := json.NewDecoder(r).Decode(doc); err != nil {
+		return nil, err
+	}
+	if doc.Version != JSONVersion {
+		return nil, fmt.Errorf("Unsupported JSON schema version: %d", doc.Version)
+	}
+
+	datfh := NewFile(doc.Signature)
+	datfh.ContentRevision = doc.ContentRevision
+	categories := [][]*Thing{doc.Items, doc.Outfits, doc.Effects, doc.Missiles}
+	for typ, things := range categories {
+		firstID := firstThingID(typeNames[typ])
+		for i, thing := range things {
+			if thing == nil {
+				return nil, fmt.Errorf("Missing thing at index %d of %s", i, typeNames[typ])
+			}
+			if thing.Type != typeNames[typ] {
+				return nil, fmt.Errorf("Thing %d of type %q listed as %s",
+					thing.ID, thing.Type, typeNames[typ])
+			}
+			if expectedID := firstID + uint16(i); thing.ID != expectedID {
+				return nil, fmt.Errorf("Expected %s %d at index %d, got %d",
+					thing.Type, expectedID, i, thing.ID)
+			}
+			datfh.AppendThing(thing)
+		}
+	}
+	datfh.resetCounts()
+	return datfh, nil
+}
+
+// MarshalJSON implements json.Marshaler interface
+func (thing *Thing) MarshalJSON() ([]byte, error) {
+	return thing.marshalJSON(false)
+}
+
+func (thing *Thing) marshalJSON(rgb bool) ([]byte, error) {
+	doc := &jsonThing{
+		ID:           thing.ID,
+		Type:         thing.Type,
+		Attributes:   make([]json.RawMessage, 0, len(thing.Attributes)),
+		SpriteGroups: thing.SpriteGroups,
+	}
+	for _, attr := range thing.Attributes {
+		data, err := marshalAttributeJSON(attr, rgb)
+		if err != nil {
+			return nil, err
+		}
+		doc.Attributes = append(doc.Attributes, data)
+	}
+	return json.Marshal(doc)
+}
+
+// UnmarshalJSON implements json.Unmarshaler interface
+func (thing *Thing) UnmarshalJSON(data []byte) error {
+	doc := &jsonThing{}
+	if err := json.Unmarshal(data, doc); err != nil {
+		return err
+	}
+	thing.ID = doc.ID
+	thing.Type = doc.Type
+	thing.SpriteGroups = doc.SpriteGroups
+	thing.Attributes = make([]Attribute, 0, len(doc.Attributes))
+	for _, data := range doc.Attributes {
+		attr, err := unmarshalAttributeJSON(data)
+		if err != nil {
+			return err
+		}
+		thing.Attributes = append(thing.Attributes, attr)
+	}
+	return nil
+}
+
+func marshalAttributeJSON(attr Attribute, rgb bool) (json.RawMessage, error) {
+	data, err := json.Marshal(attr)
+	if err != nil {
+		return nil, err
+	}
+	fields := make(map[string]json.RawMessage)
+	if err = json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	name := attr.attrName()
+	if name == "" {
+		name = attr.OpCode().String()
+	}
+	if fields["name"], err = json.Marshal(name); err != nil {
+		return nil, err
+	}
+	if colored, ok := attr.(coloredAttribute); ok && rgb {
+		if fields["rgb"], err = json.Marshal(FormatRGB(colored.RGB())); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(fields)
+}
+
+func unmarshalAttributeJSON(data json.RawMessage) (Attribute, error) {
+	var base struct {
+		Name string `json:"name"`
+		RGB  string `json:"rgb"`
+	}
+	if err := json.Unmarshal(data, &base); err != nil {
+		return nil, err
+	}
+	attr, err := newAttributeByName(base.Name)
+	if err != nil {
+		return nil, err
+	}
+	if err = applyRGB(attr, base.RGB); err != nil {
+		return nil, err
+	}
+	if err = json.Unmarshal(data, attr); err != nil {
+		return nil, err
+	}
+	return attr, nil
+}
@@ -0,0 +1,59 @@
+package dat
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+	data := encodeTestFile(t, newTestFile(9))
+	datfh, err := openSequential(writeTestFile(t, data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buf := &bytes.Buffer{}
+	if err = datfh.WriteJSON(buf); err != nil {
+		t.Fatal(err)
+	}
+	if datfh, err = ReadJSON(buf); err != nil {
+		t.Fatal(err)
+	}
+	if got := encodeTestFile(t, datfh); !bytes.Equal(got, data) {
+		t.Errorf("binary of JSON round trip differs: got %d bytes, want %d bytes", len(got), len(data))
+	}
+}
+
+func TestReadJSONThings(t *testing.T) {
+	thing := func(id int, typ string) string {
+		return fmt.Sprintf(`{"id": %d, "type": %q, "attributes": [], "spriteGroups": []}`, id, typ)
+	}
+	tests := []struct {
+		name   string
+		items  []string
+		effect string
+		valid  bool
+	}{
+		{"sequential IDs", []string{thing(100, ITEM), thing(101, ITEM)}, thing(1, EFFECT), true},
+		{"null thing", []string{"null"}, thing(1, EFFECT), false},
+		{"gap in IDs", []string{thing(100, ITEM), thing(102, ITEM)}, thing(1, EFFECT), false},
+		{"IDs out of order", []string{thing(101, ITEM), thing(100, ITEM)}, thing(1, EFFECT), false},
+		{"effect ID from 0", []string{thing(100, ITEM)}, thing(0, EFFECT), false},
+		{"wrong type", []string{thing(100, OUTFIT)}, thing(1, EFFECT), false},
+	}
+	for _, test := range tests {
+		doc := fmt.Sprintf(`{"version": 1, "signature": 1, "items": [%s], "outfits": [],
+			"effects": [%s], "missiles": []}`, strings.Join(test.items, ", "), test.effect)
+		datfh, err := ReadJSON(strings.NewReader(doc))
+		switch {
+		case test.valid && err != nil:
+			t.Errorf("%s: %v", test.name, err)
+		case test.valid && (len(datfh.Items) != 2 || datfh.Effects[0].ID != 1):
+			t.Errorf("%s: got %d items and %d effects", test.name, len(datfh.Items), len(datfh.Effects))
+		case !test.valid && err == nil:
+			t.Errorf("%s: ReadJSON succeeded, want error", test.name)
+		}
+	}
+}
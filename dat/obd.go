@@ -0,0 +1,287 @@
+package dat
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"os"
+
+	bin "github.com/go-otserv/encoding/binary"
+)
+
+// Versions of Object Builder .obd files. Version 1 files have no version
+// marker, they start with client version
+const (
+	OBDVersion1 = 100
+	OBDVersion2 = 200
+)
+
+// obdSpriteSize is size of side of sprite stored in .obd file, pixels are
+// stored as 32-bit ARGB
+const obdSpriteSize = 32
+
+// obdCategories maps thing types to categories of version 2 .obd files
+var obdCategories = map[string]uint8{ITEM: 1, OUTFIT: 2, EFFECT: 3, MISSILE: 4}
+
+// OBD holds single thing exchanged as Object Builder .obd file, together with
+// images of its sprites indexed by sprite ID. Attributes are stored in the
+// same layout as in .dat files
+type OBD struct {
+	Version       int
+	ClientVersion uint16
+	Thing         *Thing
+	Sprites       map[uint32]*image.RGBA
+}
+
+// NewOBD creates .obd version 2 document of thing, taking images of its
+// sprites from given source, e.g. spr.File
+func NewOBD(thing *Thing, sprites SpriteSource, clientVersion uint16) (*OBD, error) {
+	if len(thing.SpriteGroups) != 1 {
+		return nil, fmt.Errorf("%s %d has %d sprite groups, .obd can hold exactly one",
+			thing.Type, thing.ID, len(thing.SpriteGroups))
+	}
+	obd := &OBD{
+		Version:       OBDVersion2,
+		ClientVersion: clientVersion,
+		Thing:         thing,
+		Sprites:       make(map[uint32]*image.RGBA),
+	}
+	for _, sprID := range thing.SpriteGroups[0].Sprites {
+		if _, ok := obd.Sprites[sprID]; ok || sprID == 0 {
+			continue
+		}
+		img, err := sprites.GetSprite(int(sprID))
+		if err != nil {
+			return nil, err
+		}
+		obd.Sprites[sprID] = img
+	}
+	return obd, nil
+}
+
+// OpenOBD reads .obd file at given path
+func OpenOBD(path string) (*OBD, error) {
+	fh, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+	return ReadOBD(fh)
+}
+
+// ReadOBD reads zlib compressed .obd document of version 1 or 2. Phases of
+// animations without durations get durations the client uses for legacy
+// animations
+func ReadOBD(r io.Reader) (*OBD, error) {
+	zr, err := zlib.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	obdfh := bin.NewBufferedReader(zr)
+
+	obd := &OBD{Sprites: make(map[uint32]*image.RGBA)}
+	var marker uint16
+	if marker, err = obdfh.UInt16(); err != nil {
+		return nil, err
+	}
+
+	var typ string
+	switch marker {
+	case OBDVersion2:
+		obd.Version = OBDVersion2
+		if obd.ClientVersion, err = obdfh.UInt16(); err != nil {
+			return nil, err
+		}
+		var category uint8
+		if category, err = obdfh.UInt8(); err != nil {
+			return nil, err
+		}
+		for name, value := range obdCategories {
+			if value == category {
+				typ = name
+			}
+		}
+		// position of sprite group information
+		if _, err = obdfh.UInt32(); err != nil {
+			return nil, err
+		}
+	default:
+		obd.Version = OBDVersion1
+		obd.ClientVersion = marker
+		if typ, err = obdfh.String(); err != nil {
+			return nil, err
+		}
+	}
+	if _, ok := obdCategories[typ]; !ok {
+		return nil, fmt.Errorf("Unknown .obd thing category %q", typ)
+	}
+
+	obd.Thing = NewThing(0, typ)
+	if err = obd.Thing.deserializeAttributes(obdfh, defaultCodec); err != nil {
+		return nil, err
+	}
+	sprGr, sprCount, err := deserializeSpriteGroupInfo(typ, obdfh, obd.features())
+	if err != nil {
+		return nil, err
+	}
+	sprGr.Group = 1
+
+	for sprNum := 0; sprNum < sprCount; sprNum++ {
+		var sprID, pixelsLen uint32
+		if sprID, err = obdfh.UInt32(); err != nil {
+			return nil, err
+		}
+		if pixelsLen, err = obdfh.UInt32(); err != nil {
+			return nil, err
+		}
+		if pixelsLen != 4*obdSpriteSize*obdSpriteSize {
+			return nil, fmt.Errorf("Invalid size of sprite %d pixels: %d", sprID, pixelsLen)
+		}
+		pixels := make([]byte, pixelsLen)
+		if _, err = io.ReadFull(obdfh, pixels); err != nil {
+			return nil, err
+		}
+		sprGr.Sprites = append(sprGr.Sprites, sprID)
+		obd.Sprites[sprID] = decodeARGB(pixels)
+	}
+	obd.Thing.SpriteGroups = []*SpriteGroup{sprGr}
+	return obd, nil
+}
+
+// Save writes document as .obd file at given path
+func (obd *OBD) Save(path string) error {
+	fh, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err = obd.Write(fh); err != nil {
+		fh.Close()
+		return err
+	}
+	return fh.Close()
+}
+
+// Write writes document as zlib compressed .obd of its Version. Sprites
+// missing in Sprites are written as transparent
+func (obd *OBD) Write(w io.Writer) error {
+	if len(obd.Thing.SpriteGroups) != 1 {
+		return fmt.Errorf("%s %d has %d sprite groups, .obd can hold exactly one",
+			obd.Thing.Type, obd.Thing.ID, len(obd.Thing.SpriteGroups))
+	}
+	buf := &bytes.Buffer{}
+	obdfh := bin.NewBufferedWriter(buf)
+
+	var err error
+	switch obd.Version {
+	case OBDVersion2:
+		category, ok := obdCategories[obd.Thing.Type]
+		if !ok {
+			return fmt.Errorf("Unknown thing type %q", obd.Thing.Type)
+		}
+		if err = obdfh.PutUInt16(OBDVersion2); err != nil {
+			return err
+		}
+		if err = obdfh.PutUInt16(obd.ClientVersion); err != nil {
+			return err
+		}
+		if err = obdfh.PutUInt8(category); err != nil {
+			return err
+		}
+		// position of sprite group information, patched below
+		if err = obdfh.PutUInt32(0); err != nil {
+			return err
+		}
+	case OBDVersion1:
+		if err = obdfh.PutUInt16(obd.ClientVersion); err != nil {
+			return err
+		}
+		if err = obdfh.PutString(obd.Thing.Type); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("Unsupported .obd version: %d", obd.Version)
+	}
+
+	for _, attr := range obd.Thing.Attributes {
+		if err = serializeAttribute(attr, obdfh); err != nil {
+			return err
+		}
+	}
+	if err = obdfh.PutUInt8(uint8(OpEnd)); err != nil {
+		return err
+	}
+	if err = obdfh.Flush(); err != nil {
+		return err
+	}
+	sprGrPos := buf.Len()
+
+	sprGr := obd.Thing.SpriteGroups[0]
+	if err = sprGr.serializeInfo(obd.Thing.Type, obdfh, obd.features()); err != nil {
+		return err
+	}
+	for _, sprID := range sprGr.Sprites {
+		if err = obdfh.PutUInt32(sprID); err != nil {
+			return err
+		}
+		if err = obdfh.PutUInt32(4 * obdSpriteSize * obdSpriteSize); err != nil {
+			return err
+		}
+		if _, err = obdfh.Write(encodeARGB(obd.Sprites[sprID])); err != nil {
+			return err
+		}
+	}
+	if err = obdfh.Flush(); err != nil {
+		return err
+	}
+
+	data := buf.Bytes()
+	if obd.Version == OBDVersion2 {
+		data[5] = byte(sprGrPos)
+		data[6] = byte(sprGrPos >> 8)
+		data[7] = byte(sprGrPos >> 16)
+		data[8] = byte(sprGrPos >> 24)
+	}
+	zw := zlib.NewWriter(w)
+	if _, err = zw.Write(data); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// features returns features of sprite group encoding. Frame groups are not
+// supported, durations of animation phases are stored since client 10.50
+func (obd *OBD) features() Features {
+	return Features{
+		Extended:           true,
+		ImprovedAnimations: obd.Version == OBDVersion2 || obd.ClientVersion >= 1050,
+	}
+}
+
+func decodeARGB(pixels []byte) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, obdSpriteSize, obdSpriteSize))
+	for i := 0; i < len(pixels); i += 4 {
+		c := color.NRGBA{pixels[i+1], pixels[i+2], pixels[i+3], pixels[i]}
+		px := i / 4
+		img.Set(px%obdSpriteSize, px/obdSpriteSize, c)
+	}
+	return img
+}
+
+func encodeARGB(img *image.RGBA) []byte {
+	pixels := make([]byte, 4*obdSpriteSize*obdSpriteSize)
+	if img == nil {
+		return pixels
+	}
+	for y := 0; y < obdSpriteSize; y++ {
+		for x := 0; x < obdSpriteSize; x++ {
+			c := color.NRGBAModel.Convert(img.At(img.Rect.Min.X+x, img.Rect.Min.Y+y)).(color.NRGBA)
+			i := 4 * (y*obdSpriteSize + x)
+			pixels[i], pixels[i+1], pixels[i+2], pixels[i+3] = c.A, c.R, c.G, c.B
+		}
+	}
+	return pixels
+}
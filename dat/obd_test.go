@@ -0,0 +1,159 @@
+package dat
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// newTestOBD returns .obd document of animated item, first sprite is red and
+// second one is missing in Sprites
+func newTestOBD(version int, clientVersion uint16) *OBD {
+	item := NewThing(0, ITEM)
+	item.Attributes = []Attribute{NewPickupable(), NewLight(4, 215),
+		NewMarket(7, 101, 101, "gold coin", 0, 0)}
+	item.SpriteGroups = []*SpriteGroup{newTestSpriteGroup(1, 1, 1, 1, 1, 1, 2, 10)}
+
+	red := image.NewRGBA(image.Rect(0, 0, obdSpriteSize, obdSpriteSize))
+	red.Set(0, 0, color.NRGBA{255, 0, 0, 255})
+	red.Set(31, 31, color.NRGBA{255, 0, 0, 255})
+	return &OBD{
+		Version:       version,
+		ClientVersion: clientVersion,
+		Thing:         item,
+		Sprites:       map[uint32]*image.RGBA{10: red},
+	}
+}
+
+func TestOBDRoundTrip(t *testing.T) {
+	legacy := uint32(legacyPhaseDuration(ITEM) / time.Millisecond)
+	tests := []struct {
+		name          string
+		version       int
+		clientVersion uint16
+		marker        uint16
+		improved      bool
+	}{
+		{"version 2", OBDVersion2, 1098, OBDVersion2, true},
+		{"version 1", OBDVersion1, 1098, 1098, true},
+		{"version 1 legacy animations", OBDVersion1, 1010, 1010, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obd := newTestOBD(tt.version, tt.clientVersion)
+			buf := &bytes.Buffer{}
+			if err := obd.Write(buf); err != nil {
+				t.Fatal(err)
+			}
+
+			zr, err := zlib.NewReader(bytes.NewReader(buf.Bytes()))
+			if err != nil {
+				t.Fatalf("document is not zlib compressed: %v", err)
+			}
+			data, err := io.ReadAll(zr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if marker := binary.LittleEndian.Uint16(data); marker != tt.marker {
+				t.Errorf("expected marker %d, got %d", tt.marker, marker)
+			}
+			if tt.version == OBDVersion2 {
+				if category := data[4]; category != obdCategories[ITEM] {
+					t.Errorf("expected category %d, got %d", obdCategories[ITEM], category)
+				}
+				sprGrPos := binary.LittleEndian.Uint32(data[5:])
+				if sprGrPos == 0 || int(sprGrPos) >= len(data) || data[sprGrPos-1] != uint8(OpEnd) {
+					t.Errorf("position of sprite group information %d does not follow attributes", sprGrPos)
+				}
+			}
+
+			got, err := ReadOBD(buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Version != tt.version || got.ClientVersion != tt.clientVersion {
+				t.Errorf("expected version %d of client %d, got %d of client %d",
+					tt.version, tt.clientVersion, got.Version, got.ClientVersion)
+			}
+			if got.Thing.Type != ITEM {
+				t.Errorf("expected %s, got %s", ITEM, got.Thing.Type)
+			}
+			if !reflect.DeepEqual(got.Thing.Attributes, obd.Thing.Attributes) {
+				t.Errorf("expected attributes %v, got %v", obd.Thing.Attributes, got.Thing.Attributes)
+			}
+
+			want := newTestSpriteGroup(1, 1, 1, 1, 1, 1, 2, 10)
+			if !tt.improved {
+				want.Async = 0
+				want.AnimationPhases = []*AnimationPhase{{legacy, legacy}, {legacy, legacy}}
+			}
+			if len(got.Thing.SpriteGroups) != 1 || !reflect.DeepEqual(got.Thing.SpriteGroups[0], want) {
+				t.Errorf("expected sprite group %+v, got %+v", want, got.Thing.SpriteGroups)
+			}
+
+			if len(got.Sprites) != 2 {
+				t.Fatalf("expected 2 sprites, got %d", len(got.Sprites))
+			}
+			red := color.RGBA{255, 0, 0, 255}
+			if c := got.Sprites[10].RGBAAt(0, 0); c != red {
+				t.Errorf("expected red pixel of sprite 10, got %v", c)
+			}
+			if c := got.Sprites[10].RGBAAt(31, 31); c != red {
+				t.Errorf("expected red last pixel of sprite 10, got %v", c)
+			}
+			if c := got.Sprites[10].RGBAAt(1, 0); c != (color.RGBA{}) {
+				t.Errorf("expected transparent pixel of sprite 10, got %v", c)
+			}
+			if !bytes.Equal(encodeARGB(got.Sprites[11]), encodeARGB(nil)) {
+				t.Error("expected missing sprite 11 to be transparent")
+			}
+		})
+	}
+}
+
+func TestOBDErrors(t *testing.T) {
+	if _, err := ReadOBD(bytes.NewReader([]byte("not zlib"))); err == nil {
+		t.Error("expected error reading uncompressed document")
+	}
+
+	compress := func(data []byte) *bytes.Buffer {
+		buf := &bytes.Buffer{}
+		zw := zlib.NewWriter(buf)
+		zw.Write(data)
+		zw.Close()
+		return buf
+	}
+	// version 1 document of unknown category "tile"
+	if _, err := ReadOBD(compress([]byte{0x4A, 0x04, 4, 0, 't', 'i', 'l', 'e'})); err == nil {
+		t.Error("expected error reading unknown category")
+	}
+	// version 2 document of category 9
+	if _, err := ReadOBD(compress([]byte{200, 0, 0x4A, 0x04, 9, 0, 0, 0, 0})); err == nil {
+		t.Error("expected error reading unknown category")
+	}
+	// truncated document
+	if _, err := ReadOBD(compress([]byte{200, 0, 0x4A})); err == nil {
+		t.Error("expected error reading truncated document")
+	}
+
+	obd := newTestOBD(3, 1098)
+	if err := obd.Write(io.Discard); err == nil {
+		t.Error("expected error writing unsupported version")
+	}
+	obd = newTestOBD(OBDVersion2, 1098)
+	obd.Thing.SpriteGroups = append(obd.Thing.SpriteGroups, newTestSpriteGroup(1, 1, 1, 1, 1, 1, 1, 20))
+	if err := obd.Write(io.Discard); err == nil {
+		t.Error("expected error writing two sprite groups")
+	}
+	obd = newTestOBD(OBDVersion2, 1098)
+	obd.Thing.Type = "tile"
+	if err := obd.Write(io.Discard); err == nil {
+		t.Error("expected error writing unknown thing type")
+	}
+}
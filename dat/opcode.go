@@ -0,0 +1,152 @@
+package dat
+
+import "fmt"
+
+// OpCode identifies attribute type in .dat file
+type OpCode uint8
+
+// Attribute opcodes
+const (
+	OpGround          OpCode = 0
+	OpGroundBorder    OpCode = 1
+	OpOnBottom        OpCode = 2
+	OpOnTop           OpCode = 3
+	OpContainer       OpCode = 4
+	OpStackable       OpCode = 5
+	OpForceUse        OpCode = 6
+	OpMultiUse        OpCode = 7
+	OpWritable        OpCode = 8
+	OpWritableOnce    OpCode = 9
+	OpFluidContainer  OpCode = 10
+	OpSplash          OpCode = 11
+	OpNotWalkable     OpCode = 12
+	OpNotMoveable     OpCode = 13
+	OpBlockProjectile OpCode = 14
+	OpNotPathable     OpCode = 15
+	OpNoMoveAnimation OpCode = 16
+	OpPickupable      OpCode = 17
+	OpHangable        OpCode = 18
+	OpHookSouth       OpCode = 19
+	OpHookEast        OpCode = 20
+	OpRotateable      OpCode = 21
+	OpLight           OpCode = 22
+	OpDontHide        OpCode = 23
+	OpTranslucent     OpCode = 24
+	OpDisplacement    OpCode = 25
+	OpElevation       OpCode = 26
+	OpLyingCorpse     OpCode = 27
+	OpAnimateAlways   OpCode = 28
+	OpMinimapColor    OpCode = 29
+	OpLensHelp        OpCode = 30
+	OpFullGround      OpCode = 31
+	OpLook            OpCode = 32
+	OpCloth           OpCode = 33
+	OpMarket          OpCode = 34
+	OpUsable          OpCode = 35
+	OpWrapable        OpCode = 36
+	OpUnwrapable      OpCode = 37
+	OpTopEffect       OpCode = 38
+	OpOpacity         OpCode = 100
+	OpNotPrewalkable  OpCode = 101
+	OpFloorChange     OpCode = 252
+	OpDeprecated      OpCode = 254
+	OpEnd             OpCode = 255
+)
+
+var opNames = map[OpCode]string{
+	OpGround:          "ground",
+	OpGroundBorder:    "groundBorder",
+	OpOnBottom:        "onBottom",
+	OpOnTop:           "onTop",
+	OpContainer:       "container",
+	OpStackable:       "stackable",
+	OpForceUse:        "forceUse",
+	OpMultiUse:        "multiUse",
+	OpWritable:        "writable",
+	OpWritableOnce:    "writableOnce",
+	OpFluidContainer:  "fluidContainer",
+	OpSplash:          "splash",
+	OpNotWalkable:     "notWalkable",
+	OpNotMoveable:     "notMoveable",
+	OpBlockProjectile: "blockProjectile",
+	OpNotPathable:     "notPathable",
+	OpNoMoveAnimation: "noMoveAnimation",
+	OpPickupable:      "pickupable",
+	OpHangable:        "hangable",
+	OpHookSouth:       "hookSouth",
+	OpHookEast:        "hookEast",
+	OpRotateable:      "rotateable",
+	OpLight:           "light",
+	OpDontHide:        "dontHide",
+	OpTranslucent:     "translucent",
+	OpDisplacement:    "displacement",
+	OpElevation:       "elevation",
+	OpLyingCorpse:     "lyingCorpse",
+	OpAnimateAlways:   "animateAlways",
+	OpMinimapColor:    "minimapColor",
+	OpLensHelp:        "lensHelp",
+	OpFullGround:      "fullGround",
+	OpLook:            "look",
+	OpCloth:           "cloth",
+	OpMarket:          "market",
+	OpUsable:          "usable",
+	OpWrapable:        "wrapable",
+	OpUnwrapable:      "unwrapable",
+	OpTopEffect:       "topEffect",
+	OpOpacity:         "opacity",
+	OpNotPrewalkable:  "notPrewalkable",
+	OpFloorChange:     "floorChange",
+	OpDeprecated:      "deprecated",
+	OpEnd:             "end",
+}
+
+// String returns attribute name for given opcode
+func (op OpCode) String() string {
+	if name, ok := opNames[op]; ok {
+		return name
+	}
+	if custom := lookupCustomAttribute(op); custom != nil {
+		return custom.name
+	}
+	return fmt.Sprintf("OpCode<%d>", uint8(op))
+}
+
+// opCodeByName returns opcode of attribute with given name. OpEnd marks end
+// of attributes and isn't attribute itself, so "end" isn't accepted
+func opCodeByName(name string) (OpCode, bool) {
+	for op, opName := range opNames {
+		if opName == name && op != OpEnd {
+			return op, true
+		}
+	}
+	return customOpCodeByName(name)
+}
+
+// Flags is a bitset of attribute opcodes
+type Flags [4]uint64
+
+// Has reports whether opcode is set
+func (f Flags) Has(op OpCode) bool {
+	return f[op/64]&(1<<(op%64)) != 0
+}
+
+// Set adds opcode to bitset
+func (f *Flags) Set(op OpCode) {
+	f[op/64] |= 1 << (op % 64)
+}
+
+// Clear removes opcode from bitset
+func (f *Flags) Clear(op OpCode) {
+	f[op/64] &^= 1 << (op % 64)
+}
+
+// OpCodes returns all opcodes set in bitset in ascending order
+func (f Flags) OpCodes() []OpCode {
+	ops := make([]OpCode, 0)
+	for op := 0; op < 256; op++ {
+		if f.Has(OpCode(op)) {
+			ops = append(ops, OpCode(op))
+		}
+	}
+	return ops
+}
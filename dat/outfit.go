@@ -0,0 +1,206 @@
+package dat
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// Outfit palette consists of 19 hues, each in 7 saturation / intensity
+// variants
+const (
+	outfitHueSteps = 19
+	outfitSIValues = 7
+	// OutfitColorsCount is number of colors in outfit palette
+	OutfitColorsCount = outfitHueSteps * outfitSIValues
+)
+
+// Directions outfit might face, they are PatternX of outfit sprite group
+const (
+	North = iota
+	East
+	South
+	West
+)
+
+// Outfit addons, they are PatternY of outfit sprite group
+const (
+	AddonFirst  uint8 = 1
+	AddonSecond uint8 = 2
+)
+
+// OutfitPalette holds 133 colors in which outfit parts can be painted
+var OutfitPalette = newOutfitPalette()
+
+// Outfit describes look of creature. Head, Body, Legs and Feet are indexes
+// into OutfitPalette, Addons is bitmask of AddonFirst and AddonSecond,
+// Direction is one of North, East, South, West
+type Outfit struct {
+	Head      uint8
+	Body      uint8
+	Legs      uint8
+	Feet      uint8
+	Addons    uint8
+	Mounted   bool
+	Direction int
+}
+
+// OutfitColor returns color of outfit palette of given index. Indexes out of
+// range are treated as 0
+func OutfitColor(idx uint8) color.RGBA {
+	if int(idx) >= OutfitColorsCount {
+		idx = 0
+	}
+	return OutfitPalette[idx]
+}
+
+func newOutfitPalette() []color.RGBA {
+	palette := make([]color.RGBA, OutfitColorsCount)
+	for idx := range palette {
+		palette[idx] = hsiColor(idx)
+	}
+	return palette
+}
+
+// hsiColor computes color of outfit palette the same way the client does
+func hsiColor(idx int) color.RGBA {
+	var hue, saturation, intensity float64
+
+	if idx%outfitHueSteps != 0 {
+		hue = float64(idx%outfitHueSteps) / 18.0
+		switch idx / outfitHueSteps {
+		case 0:
+			saturation, intensity = 0.25, 1.00
+		case 1:
+			saturation, intensity = 0.25, 0.75
+		case 2:
+			saturation, intensity = 0.50, 0.75
+		case 3:
+			saturation, intensity = 0.667, 0.75
+		case 4:
+			saturation, intensity = 1.00, 1.00
+		case 5:
+			saturation, intensity = 1.00, 0.75
+		case 6:
+			saturation, intensity = 1.00, 0.50
+		}
+	} else {
+		intensity = 1 - float64(idx)/outfitHueSteps/outfitSIValues
+	}
+
+	if intensity == 0 {
+		return color.RGBA{0, 0, 0, 255}
+	}
+	if saturation == 0 {
+		gray := uint8(intensity * 255)
+		return color.RGBA{gray, gray, gray, 255}
+	}
+
+	var r, g, b float64
+	switch {
+	case hue < 1.0/6.0:
+		r = intensity
+		b = intensity * (1 - saturation)
+		g = b + (intensity-b)*6*hue
+	case hue < 2.0/6.0:
+		g = intensity
+		b = intensity * (1 - saturation)
+		r = g - (intensity-b)*(6*hue-1)
+	case hue < 3.0/6.0:
+		g = intensity
+		r = intensity * (1 - saturation)
+		b = r + (intensity-r)*(6*hue-2)
+	case hue < 4.0/6.0:
+		b = intensity
+		r = intensity * (1 - saturation)
+		g = b - (intensity-r)*(6*hue-3)
+	case hue < 5.0/6.0:
+		b = intensity
+		g = intensity * (1 - saturation)
+		r = g + (intensity-g)*(6*hue-4)
+	default:
+		r = intensity
+		g = intensity * (1 - saturation)
+		b = r - (intensity-g)*(6*hue-5)
+	}
+	return color.RGBA{uint8(r * 255), uint8(g * 255), uint8(b * 255), 255}
+}
+
+// RenderOutfit renders outfit thing of given sprite group and animation frame
+// the way the client does. The first layer holds outfit sprites, the second
+// one (if present) is a mask: yellow, red, green and blue pixels of mask mark
+// head, body, legs and feet painted with colors of given outfit. Base outfit
+// (PatternY 0) is drawn first, followed by selected addons; PatternZ 1 is used
+// when outfit is mounted
+func RenderOutfit(thing *Thing, sprites SpriteSource, outfit Outfit, group, frame int) (*image.RGBA, error) {
+	opts := RenderOptions{Group: group, PatternX: outfit.Direction, Frame: frame}
+	if group >= 0 && group < len(thing.SpriteGroups) {
+		sprGr := thing.SpriteGroups[group]
+		if outfit.Mounted && sprGr.PatternZNum > 1 {
+			opts.PatternZ = 1
+		}
+	}
+
+	var canvas *image.RGBA
+	for py := 0; py < 3; py++ {
+		if py > 0 && outfit.Addons&(1<<uint(py-1)) == 0 {
+			continue
+		}
+		if py > 0 && py >= int(thing.SpriteGroups[group].PatternYNum) {
+			break
+		}
+		opts.PatternY = py
+		img, err := renderColorized(thing, sprites, opts, outfit)
+		if err != nil {
+			return nil, err
+		}
+		if canvas == nil {
+			canvas = img
+			continue
+		}
+		draw.Draw(canvas, canvas.Rect, img, image.Point{}, draw.Over)
+	}
+	return canvas, nil
+}
+
+func renderColorized(thing *Thing, sprites SpriteSource, opts RenderOptions, outfit Outfit) (*image.RGBA, error) {
+	opts.Layers = []int{0}
+	base, err := Render(thing, sprites, opts)
+	if err != nil {
+		return nil, err
+	}
+	if thing.SpriteGroups[opts.Group].Layers < 2 {
+		return base, nil
+	}
+	opts.Layers = []int{1}
+	mask, err := Render(thing, sprites, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	head, body := OutfitColor(outfit.Head), OutfitColor(outfit.Body)
+	legs, feet := OutfitColor(outfit.Legs), OutfitColor(outfit.Feet)
+	for i := 0; i < len(base.Pix); i += 4 {
+		if mask.Pix[i+3] == 0 {
+			continue
+		}
+		r, g, b := mask.Pix[i], mask.Pix[i+1], mask.Pix[i+2]
+		var paint color.RGBA
+		switch {
+		case r > 0 && g > 0 && b == 0:
+			paint = head
+		case r > 0 && g == 0 && b == 0:
+			paint = body
+		case r == 0 && g > 0 && b == 0:
+			paint = legs
+		case r == 0 && g == 0 && b > 0:
+			paint = feet
+		default:
+			continue
+		}
+		base.Pix[i] = uint8(uint16(base.Pix[i]) * uint16(paint.R) / 255)
+		base.Pix[i+1] = uint8(uint16(base.Pix[i+1]) * uint16(paint.G) / 255)
+		base.Pix[i+2] = uint8(uint16(base.Pix[i+2]) * uint16(paint.B) / 255)
+	}
+	return base, nil
+}
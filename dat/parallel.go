@@ -0,0 +1,250 @@
+package dat
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+	"runtime"
+	"sync"
+
+	bin "github.com/go-otserv/encoding/binary"
+)
+
+// headerSize is size of .dat file header: signature and four things counts
+const headerSize = 12
+
+// attributeDataSizes holds numbers of uint16 values of built-in attributes,
+// which can be skipped without decoding. Attributes missing here have no
+// data, except Market which holds string
+var attributeDataSizes = map[OpCode]int{
+	OpGround:       1,
+	OpWritable:     1,
+	OpWritableOnce: 1,
+	OpLight:        2,
+	OpDisplacement: 2,
+	OpElevation:    1,
+	OpMinimapColor: 1,
+	OpLensHelp:     1,
+	OpCloth:        1,
+	OpUsable:       1,
+}
+
+// ParallelOptions configures OpenParallel and ReadParallel. Workers is
+// number of goroutines decoding things, GOMAXPROCS if <= 0. Features,
+// Profile and Strict are set to returned File
+type ParallelOptions struct {
+	Workers  int
+	Features *Features
+	Profile  *Profile
+	Strict   bool
+}
+
+// span locates things of single category in file, Offsets holds offset of
+// every thing followed by offset of the end of the last one
+type span struct {
+	typ     string
+	firstID int
+	offsets []int64
+}
+
+// OpenParallel reads whole .dat file at given path, see ReadParallel
+func OpenParallel(path string, opts ParallelOptions) (*File, error) {
+	fh, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+	return ReadParallel(fh, opts)
+}
+
+// ReadParallel reads header and all things of .dat file. Boundaries of
+// things are found by fast sequential scan, which skips attribute data and
+// sprite IDs, then things are decoded concurrently. Result is identical to
+// Open followed by Deserialize
+func ReadParallel(r io.ReaderAt, opts ParallelOptions) (*File, error) {
+	header := make([]byte, headerSize)
+	if _, err := r.ReadAt(header, 0); err != nil {
+		return nil, err
+	}
+	datfh := NewFile(binary.LittleEndian.Uint32(header))
+	datfh.Features, datfh.Profile, datfh.Strict = opts.Features, opts.Profile, opts.Strict
+	datfh.itemsCount = int(binary.LittleEndian.Uint16(header[4:])) + 1
+	datfh.outfitsCount = int(binary.LittleEndian.Uint16(header[6:])) + 1
+	datfh.effectsCount = int(binary.LittleEndian.Uint16(header[8:])) + 1
+	datfh.missilesCount = int(binary.LittleEndian.Uint16(header[10:])) + 1
+	c := datfh.codec()
+
+	spans, err := datfh.scan(r, c)
+	if err != nil {
+		return nil, err
+	}
+
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	type job struct {
+		sp          *span
+		first, last int
+		things      []*Thing
+	}
+	jobs := make([]*job, 0)
+	results := make([][]*Thing, len(spans))
+	for i, sp := range spans {
+		count := len(sp.offsets) - 1
+		results[i] = make([]*Thing, count)
+		// every job decodes chunk of consecutive things
+		chunk := count/(4*workers) + 1
+		for first := 0; first < count; first += chunk {
+			last := first + chunk
+			if last > count {
+				last = count
+			}
+			jobs = append(jobs, &job{sp, first, last, results[i]})
+		}
+	}
+
+	errs := make([]error, len(jobs))
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, workers)
+	for i, j := range jobs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, j *job) {
+			defer wg.Done()
+			errs[i] = decodeSpan(r, j.sp, j.first, j.last, j.things, c)
+			<-sem
+		}(i, j)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	for i, sp := range spans {
+		datfh.setCategory(sp.typ, results[i])
+	}
+	datfh.next = cursor{category: len(typeNames), done: datfh.Total()}
+	return datfh, nil
+}
+
+// decodeSpan decodes things of span from first to last (exclusive) into
+// things
+func decodeSpan(r io.ReaderAt, sp *span, first, last int, things []*Thing, c codec) error {
+	start, end := sp.offsets[first], sp.offsets[last]
+	data := make([]byte, end-start)
+	if _, err := r.ReadAt(data, start); err != nil {
+		return err
+	}
+	datfh := bin.NewBufferedReader(bytes.NewReader(data))
+	for idx := first; idx < last; idx++ {
+		thing, err := deserializeThing(uint16(sp.firstID+idx), sp.typ, datfh, c)
+		if err != nil {
+			return err
+		}
+		things[idx] = thing
+	}
+	return nil
+}
+
+// scan finds offsets of all things declared in file header
+func (datfh *File) scan(r io.ReaderAt, c codec) ([]*span, error) {
+	sequential := &sequentialReader{r: r, off: headerSize}
+	scanfh := bin.NewBufferedReader(sequential)
+	offset := func() int64 {
+		return sequential.off - int64(scanfh.Buffered())
+	}
+
+	spans := make([]*span, 0, len(typeNames))
+	for _, typ := range typeNames {
+		first, end := datfh.idRange(typ)
+		sp := &span{typ: typ, firstID: first, offsets: make([]int64, 0, end-first+1)}
+		for id := first; id < end; id++ {
+			sp.offsets = append(sp.offsets, offset())
+			if err := skipThing(scanfh, typ, c); err != nil {
+				return nil, err
+			}
+		}
+		sp.offsets = append(sp.offsets, offset())
+		spans = append(spans, sp)
+	}
+	return spans, nil
+}
+
+// skipThing moves reader past single thing, decoding only what's needed to
+// find its size
+func skipThing(datfh *bin.BufferedFile, typ string, c codec) error {
+	for {
+		rawOp, err := datfh.UInt8()
+		if err != nil {
+			return err
+		}
+		if OpCode(rawOp) == OpEnd {
+			break
+		}
+		op := c.decodeOpCode(rawOp)
+		if count, ok := attributeDataSizes[op]; ok {
+			if err = skipValues(datfh, count, 2); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, builtin := opNames[op]; builtin && op != OpMarket {
+			continue
+		}
+		if _, err = deserializeAttribute(op, datfh, c.strict); err != nil {
+			return err
+		}
+	}
+
+	groupsCount := uint8(1)
+	if typ == OUTFIT && c.features.FrameGroups {
+		var err error
+		if groupsCount, err = datfh.UInt8(); err != nil {
+			return err
+		}
+	}
+	idSize := 2
+	if c.features.Extended {
+		idSize = 4
+	}
+	for group := 0; group < int(groupsCount); group++ {
+		_, sprCount, err := deserializeSpriteGroupInfo(typ, datfh, c.features)
+		if err != nil {
+			return err
+		}
+		if err = skipValues(datfh, sprCount, idSize); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sequentialReader reads io.ReaderAt sequentially starting at offset off
+type sequentialReader struct {
+	r   io.ReaderAt
+	off int64
+}
+
+func (sr *sequentialReader) Read(p []byte) (int, error) {
+	n, err := sr.r.ReadAt(p, sr.off)
+	sr.off += int64(n)
+	if err == io.EOF && n > 0 {
+		err = nil
+	}
+	return n, err
+}
+
+// skipValues skips count values of given size. Truncated data is reported
+// the same way as reading values one by one would: io.EOF if data ends
+// between values and io.ErrUnexpectedEOF if it ends inside one
+func skipValues(datfh *bin.BufferedFile, count, size int) error {
+	n, err := datfh.Discard(count * size)
+	if err == io.EOF && n%size != 0 {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
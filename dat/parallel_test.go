@@ -0,0 +1,66 @@
+package dat
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestOpenParallel(t *testing.T) {
+	path := writeTestFile(t, encodeTestFile(t, newTestFile(50)))
+	want, err := openSequential(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, workers := range []int{0, 1, 2, 3, 8, 64} {
+		got, err := OpenParallel(path, ParallelOptions{Workers: workers})
+		if err != nil {
+			t.Fatalf("workers=%d: %v", workers, err)
+		}
+		if got.Signature != want.Signature || got.Total() != want.Total() {
+			t.Errorf("workers=%d: header differs", workers)
+		}
+		for _, typ := range typeNames {
+			if !reflect.DeepEqual(got.Category(typ), want.Category(typ)) {
+				t.Errorf("workers=%d: %s differ from sequential decoding", workers, typ)
+			}
+		}
+	}
+}
+
+func TestOpenParallelTruncated(t *testing.T) {
+	data := encodeTestFile(t, newTestFile(50))
+	for size := headerSize + 1; size < len(data); size += 11 {
+		path := writeTestFile(t, data[:size])
+		_, want := openSequential(path)
+		if want == nil {
+			t.Fatalf("size=%d: sequential decoding succeeded", size)
+		}
+		for _, workers := range []int{1, 4} {
+			_, got := OpenParallel(path, ParallelOptions{Workers: workers})
+			if got == nil || got.Error() != want.Error() {
+				t.Errorf("size=%d workers=%d: got error %v, want %v", size, workers, got, want)
+			}
+		}
+	}
+}
+
+func BenchmarkDeserialize(b *testing.B) {
+	path := writeTestFile(b, encodeTestFile(b, newTestFile(20000)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := openSequential(path); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkOpenParallel(b *testing.B) {
+	path := writeTestFile(b, encodeTestFile(b, newTestFile(20000)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := OpenParallel(path, ParallelOptions{}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
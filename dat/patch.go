@@ -0,0 +1,389 @@
+package dat
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// PatchVersion is version of JSON format produced by Patch.WriteJSON
+const PatchVersion = 1
+
+// Patch holds changes made to things of one File, which can be applied to
+// another File, e.g. newer client version of the one patch was made against.
+// Every change records value it replaces, so changes to things modified in
+// both files are detected as conflicts
+type Patch struct {
+	Version int           `json:"version"`
+	Things  []*ThingPatch `json:"things"`
+}
+
+// ThingPatch holds changes of single thing. Thing is the whole added thing
+// for Added kind and the original thing for Removed kind
+type ThingPatch struct {
+	Type         string              `json:"type"`
+	ID           uint16              `json:"id"`
+	Kind         string              `json:"kind"`
+	Thing        *Thing              `json:"thing,omitempty"`
+	Attributes   []*AttributePatch   `json:"attributes,omitempty"`
+	SpriteGroups []*SpriteGroupPatch `json:"spriteGroups,omitempty"`
+}
+
+// AttributePatch replaces attribute Old with New. Old is nil for added
+// attribute, New is nil for removed one
+type AttributePatch struct {
+	OpCode OpCode
+	Old    Attribute
+	New    Attribute
+}
+
+// SpriteGroupPatch replaces sprite group at 1-based position Group. Old is
+// nil for added sprite group, New is nil for removed one
+type SpriteGroupPatch struct {
+	Group int          `json:"group"`
+	Old   *SpriteGroup `json:"old"`
+	New   *SpriteGroup `json:"new"`
+}
+
+// Conflict describes change of Patch which couldn't be applied
+type Conflict struct {
+	Type    string `json:"type"`
+	ID      uint16 `json:"id"`
+	Target  string `json:"target"`
+	Message string `json:"message"`
+}
+
+// Error implements error interface
+func (conflict *Conflict) Error() string {
+	return fmt.Sprintf("%s %d: %s: %s", conflict.Type, conflict.ID,
+		conflict.Target, conflict.Message)
+}
+
+// MakePatch creates patch holding all changes turning base into modified
+func MakePatch(base, modified *File) *Patch {
+	patch := &Patch{Version: PatchVersion, Things: make([]*ThingPatch, 0)}
+	for _, cat := range Diff(base, modified).Categories {
+		for _, id := range cat.Added {
+			patch.Things = append(patch.Things, &ThingPatch{
+				Type:  cat.Category,
+				ID:    id,
+				Kind:  Added,
+				Thing: findThing(modified.Category(cat.Category), id).Clone(),
+			})
+		}
+		for _, id := range cat.Removed {
+			patch.Things = append(patch.Things, &ThingPatch{
+				Type:  cat.Category,
+				ID:    id,
+				Kind:  Removed,
+				Thing: findThing(base.Category(cat.Category), id).Clone(),
+			})
+		}
+		for _, thingDiff := range cat.Changed {
+			patch.Things = append(patch.Things, newChangedThingPatch(thingDiff))
+		}
+	}
+	return patch
+}
+
+func newChangedThingPatch(thingDiff *ThingDiff) *ThingPatch {
+	thingPatch := &ThingPatch{Type: thingDiff.Type, ID: thingDiff.ID, Kind: Changed}
+	for _, change := range thingDiff.Attributes {
+		attrPatch := &AttributePatch{OpCode: change.OpCode}
+		if change.Old != nil {
+			attrPatch.Old = cloneAttribute(change.Old)
+		}
+		if change.New != nil {
+			attrPatch.New = cloneAttribute(change.New)
+		}
+		thingPatch.Attributes = append(thingPatch.Attributes, attrPatch)
+	}
+	for _, change := range thingDiff.SpriteGroups {
+		sprGrPatch := &SpriteGroupPatch{Group: change.Group}
+		if change.Old != nil {
+			sprGrPatch.Old = change.Old.Clone()
+		}
+		if change.New != nil {
+			sprGrPatch.New = change.New.Clone()
+		}
+		thingPatch.SpriteGroups = append(thingPatch.SpriteGroups, sprGrPatch)
+	}
+	return thingPatch
+}
+
+func findThing(things []*Thing, id uint16) *Thing {
+	for _, thing := range things {
+		if thing.ID == id {
+			return thing
+		}
+	}
+	return nil
+}
+
+// Apply applies patch to given file. Changes whose original value doesn't
+// match the file are skipped and reported as conflicts, changes already
+// present in the file are skipped silently. Things can only be added right
+// after the last thing of category and removed from its end
+func (patch *Patch) Apply(datfh *File) []*Conflict {
+	conflicts := make([]*Conflict, 0)
+
+	things := append(make([]*ThingPatch, 0, len(patch.Things)), patch.Things...)
+	// removals from the end of category first, then changes and additions
+	// in ID order
+	rank := map[string]int{Removed: 0, Changed: 1, Added: 2}
+	sort.SliceStable(things, func(i, j int) bool {
+		a, b := things[i], things[j]
+		if rank[a.Kind] != rank[b.Kind] {
+			return rank[a.Kind] < rank[b.Kind]
+		}
+		switch a.Kind {
+		case Removed:
+			return a.ID > b.ID
+		case Added:
+			return a.ID < b.ID
+		}
+		return false
+	})
+
+	for _, thingPatch := range things {
+		var conflict *Conflict
+		switch thingPatch.Kind {
+		case Added, Removed:
+			if conflict = thingPatch.checkThing(); conflict != nil {
+				break
+			}
+			if thingPatch.Kind == Added {
+				conflict = datfh.applyAdded(thingPatch)
+			} else {
+				conflict = datfh.applyRemoved(thingPatch)
+			}
+		case Changed:
+			thing := findThing(datfh.Category(thingPatch.Type), thingPatch.ID)
+			if thing == nil {
+				conflict = thingPatch.conflict("thing", "thing doesn't exist")
+				break
+			}
+			conflicts = append(conflicts, thingPatch.applyChanges(thing)...)
+		default:
+			conflict = thingPatch.conflict("thing", fmt.Sprintf("unknown kind %q", thingPatch.Kind))
+		}
+		if conflict != nil {
+			conflicts = append(conflicts, conflict)
+		}
+	}
+	datfh.resetCounts()
+	return conflicts
+}
+
+// checkThing reports conflict if thing of added or removed thing patch is
+// missing or it isn't the thing patch refers to
+func (thingPatch *ThingPatch) checkThing() *Conflict {
+	thing := thingPatch.Thing
+	if thing == nil {
+		return thingPatch.conflict("thing", "patch has no thing")
+	}
+	if thing.Type != thingPatch.Type || thing.ID != thingPatch.ID {
+		return thingPatch.conflict("thing",
+			fmt.Sprintf("patch holds %s %d", thing.Type, thing.ID))
+	}
+	return nil
+}
+
+func (datfh *File) applyAdded(thingPatch *ThingPatch) *Conflict {
+	things := datfh.Category(thingPatch.Type)
+	if existing := findThing(things, thingPatch.ID); existing != nil {
+		if DiffThings(existing, thingPatch.Thing) == nil {
+			return nil
+		}
+		return thingPatch.conflict("thing", "ID is already used by different thing")
+	}
+	if nextID := nextThingID(thingPatch.Type, things); thingPatch.ID != nextID {
+		return thingPatch.conflict("thing",
+			fmt.Sprintf("thing can't be added, next free ID is %d", nextID))
+	}
+	datfh.AppendThing(thingPatch.Thing.Clone())
+	return nil
+}
+
+func (datfh *File) applyRemoved(thingPatch *ThingPatch) *Conflict {
+	things := datfh.Category(thingPatch.Type)
+	existing := findThing(things, thingPatch.ID)
+	if existing == nil {
+		return nil
+	}
+	if DiffThings(thingPatch.Thing, existing) != nil {
+		return thingPatch.conflict("thing", "thing was changed")
+	}
+	if existing != things[len(things)-1] {
+		return thingPatch.conflict("thing", "only last thing of category can be removed")
+	}
+	datfh.setCategory(thingPatch.Type, things[:len(things)-1])
+	return nil
+}
+
+func (thingPatch *ThingPatch) applyChanges(thing *Thing) []*Conflict {
+	conflicts := make([]*Conflict, 0)
+
+	for _, attrPatch := range thingPatch.Attributes {
+		current := thing.Attribute(attrPatch.OpCode)
+		switch {
+		case attributesEqual(current, attrPatch.New):
+		case attributesEqual(current, attrPatch.Old):
+			if attrPatch.New == nil {
+				thing.UnsetAttribute(attrPatch.OpCode)
+			} else {
+				thing.SetAttribute(cloneAttribute(attrPatch.New))
+			}
+		default:
+			conflicts = append(conflicts, thingPatch.conflict(
+				"attr "+attrPatch.OpCode.String(), "attribute was changed"))
+		}
+	}
+
+	// sprite groups are removed from the end, so removals go last and in
+	// descending order
+	sprGrPatches := append(make([]*SpriteGroupPatch, 0, len(thingPatch.SpriteGroups)),
+		thingPatch.SpriteGroups...)
+	sort.SliceStable(sprGrPatches, func(i, j int) bool {
+		a, b := sprGrPatches[i], sprGrPatches[j]
+		aRemoved, bRemoved := a.Old != nil && a.New == nil, b.Old != nil && b.New == nil
+		if aRemoved != bRemoved {
+			return bRemoved
+		}
+		return aRemoved && a.Group > b.Group
+	})
+	for _, sprGrPatch := range sprGrPatches {
+		target := fmt.Sprintf("sprite group %d", sprGrPatch.Group)
+		idx := sprGrPatch.Group - 1
+		var current *SpriteGroup
+		if idx >= 0 && idx < len(thing.SpriteGroups) {
+			current = thing.SpriteGroups[idx]
+		}
+		switch {
+		case spriteGroupsEqual(current, sprGrPatch.New):
+		case !spriteGroupsEqual(current, sprGrPatch.Old):
+			conflicts = append(conflicts, thingPatch.conflict(target, "sprite group was changed"))
+		case sprGrPatch.Old == nil:
+			if idx != len(thing.SpriteGroups) {
+				conflicts = append(conflicts, thingPatch.conflict(target,
+					"sprite group can only be added after the last one"))
+				break
+			}
+			thing.SpriteGroups = append(thing.SpriteGroups, sprGrPatch.New.Clone())
+		case sprGrPatch.New == nil:
+			if idx != len(thing.SpriteGroups)-1 {
+				conflicts = append(conflicts, thingPatch.conflict(target,
+					"only the last sprite group can be removed"))
+				break
+			}
+			thing.SpriteGroups = thing.SpriteGroups[:idx]
+		default:
+			thing.SpriteGroups[idx] = sprGrPatch.New.Clone()
+		}
+	}
+	return conflicts
+}
+
+func (thingPatch *ThingPatch) conflict(target, message string) *Conflict {
+	return &Conflict{thingPatch.Type, thingPatch.ID, target, message}
+}
+
+func attributesEqual(a, b Attribute) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return a.OpCode() == b.OpCode() && len(diffAttributes(a, b)) == 0
+}
+
+func spriteGroupsEqual(a, b *SpriteGroup) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return diffSpriteGroups(a, b) == nil
+}
+
+// nextThingID returns ID of thing which would be appended to given category
+func nextThingID(typ string, things []*Thing) uint16 {
+	if len(things) > 0 {
+		return things[len(things)-1].ID + 1
+	}
+	return firstThingID(typ)
+}
+
+// WriteJSON writes patch as JSON document
+func (patch *Patch) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(patch)
+}
+
+// ReadPatch reads patch from JSON document produced by Patch.WriteJSON
+func ReadPatch(r io.Reader) (*Patch, error) {
+	patch := &Patch{}
+	if err := json.NewDecoder(r).Decode(patch); err != nil {
+		return nil, err
+	}
+	if patch.Version != PatchVersion {
+		return nil, fmt.Errorf("Unsupported patch version: %d", patch.Version)
+	}
+	for i, thingPatch := range patch.Things {
+		if thingPatch == nil {
+			return nil, fmt.Errorf("Missing thing patch at index %d", i)
+		}
+		if (thingPatch.Kind == Added || thingPatch.Kind == Removed) && thingPatch.Thing == nil {
+			return nil, fmt.Errorf("Patch of %s %d %s has no thing",
+				thingPatch.Type, thingPatch.ID, thingPatch.Kind)
+		}
+	}
+	return patch, nil
+}
+
+// jsonAttributePatch is JSON representation of AttributePatch
+type jsonAttributePatch struct {
+	OpCode OpCode          `json:"opcode"`
+	Old    json.RawMessage `json:"old"`
+	New    json.RawMessage `json:"new"`
+}
+
+// MarshalJSON implements json.Marshaler interface
+func (attrPatch *AttributePatch) MarshalJSON() ([]byte, error) {
+	var err error
+	doc := &jsonAttributePatch{OpCode: attrPatch.OpCode}
+	if doc.Old, err = marshalOptionalAttributeJSON(attrPatch.Old); err != nil {
+		return nil, err
+	}
+	if doc.New, err = marshalOptionalAttributeJSON(attrPatch.New); err != nil {
+		return nil, err
+	}
+	return json.Marshal(doc)
+}
+
+// UnmarshalJSON implements json.Unmarshaler interface
+func (attrPatch *AttributePatch) UnmarshalJSON(data []byte) error {
+	var err error
+	doc := &jsonAttributePatch{}
+	if err = json.Unmarshal(data, doc); err != nil {
+		return err
+	}
+	attrPatch.OpCode = doc.OpCode
+	if attrPatch.Old, err = unmarshalOptionalAttributeJSON(doc.Old); err != nil {
+		return err
+	}
+	attrPatch.New, err = unmarshalOptionalAttributeJSON(doc.New)
+	return err
+}
+
+func marshalOptionalAttributeJSON(attr Attribute) (json.RawMessage, error) {
+	if attr == nil {
+		return json.RawMessage("null"), nil
+	}
+	return marshalAttributeJSON(attr, false)
+}
+
+func unmarshalOptionalAttributeJSON(data json.RawMessage) (Attribute, error) {
+	if len(data) == 0 || string(data) == "null" {
+		return nil, nil
+	}
+	return unmarshalAttributeJSON(data)
+}
@@ -0,0 +1,105 @@
+package dat
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestPatchRoundTrip(t *testing.T) {
+	base := newTestFile(6)
+	modified := newTestFile(7)
+	modified.Items[0].SetAttribute(NewMinimapColor(40))
+	modified.Items[1].UnsetAttribute(OpStackable)
+
+	buf := &bytes.Buffer{}
+	if err := MakePatch(base, modified).WriteJSON(buf); err != nil {
+		t.Fatal(err)
+	}
+	patch, err := ReadPatch(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conflicts := patch.Apply(base); len(conflicts) != 0 {
+		t.Fatalf("unexpected conflicts: %v", conflicts)
+	}
+	if diff := Diff(base, modified); !diff.Empty() {
+		t.Errorf("patched file differs from modified one")
+	}
+}
+
+func TestPatchRemoveSpriteGroups(t *testing.T) {
+	base := newTestFile(3)
+	base.Items[0].SpriteGroups = append(base.Items[0].SpriteGroups,
+		newTestSpriteGroup(1, 1, 1, 1, 1, 1, 1, 5), newTestSpriteGroup(1, 1, 1, 1, 1, 1, 1, 6))
+	modified := newTestFile(3)
+
+	if conflicts := MakePatch(base, modified).Apply(base); len(conflicts) != 0 {
+		t.Fatalf("unexpected conflicts: %v", conflicts)
+	}
+	if n := len(base.Items[0].SpriteGroups); n != 1 {
+		t.Errorf("item has %d sprite groups, want 1", n)
+	}
+}
+
+func TestPatchNewerBase(t *testing.T) {
+	base := newTestFile(6)
+	modified := newTestFile(6)
+	modified.Items[0].SetAttribute(NewMinimapColor(40))
+	modified.Items[3].SetAttribute(NewMinimapColor(50))
+	modified.AppendThing(NewThing(106, ITEM))
+	modified.Items[6].SpriteGroups = []*SpriteGroup{newTestSpriteGroup(1, 1, 1, 1, 1, 1, 1, 7)}
+	patch := MakePatch(base, modified)
+
+	// newer base changed the first item in a different way and added
+	// light to another one
+	newer := newTestFile(6)
+	newer.Items[0].SetAttribute(NewMinimapColor(41))
+	newer.Items[2].SetAttribute(NewLight(2, 10))
+
+	conflicts := patch.Apply(newer)
+	if len(conflicts) != 1 {
+		t.Fatalf("got conflicts %v, want 1", conflicts)
+	}
+	if c := conflicts[0]; c.Type != ITEM || c.ID != 100 || c.Target != "attr minimapColor" {
+		t.Errorf("got conflict %v, want minimap color of item 100", c)
+	}
+	if color := newer.Items[0].Attribute(OpMinimapColor).(*MinimapColor).Val; color != 41 {
+		t.Errorf("conflicting change was applied, minimap color is %d", color)
+	}
+	if color := newer.Items[3].Attribute(OpMinimapColor).(*MinimapColor).Val; color != 50 {
+		t.Errorf("minimap color of item 103 is %d, want 50", color)
+	}
+	if !newer.Items[2].Has(OpLight) || len(newer.Items) != 7 || newer.Total() != 10 {
+		t.Errorf("changes of newer base were lost or thing wasn't added")
+	}
+}
+
+func TestReadPatchMissingThing(t *testing.T) {
+	docs := []string{
+		`{"version": 1, "things": [null]}`,
+		`{"version": 1, "things": [{"type": "item", "id": 100, "kind": "added"}]}`,
+		`{"version": 1, "things": [{"type": "item", "id": 100, "kind": "removed", "thing": null}]}`,
+	}
+	for _, doc := range docs {
+		if _, err := ReadPatch(strings.NewReader(doc)); err == nil {
+			t.Errorf("ReadPatch(%s) succeeded, want error", doc)
+		}
+	}
+}
+
+func TestApplyMissingThing(t *testing.T) {
+	datfh := newTestFile(3)
+	patch := &Patch{Version: PatchVersion, Things: []*ThingPatch{
+		{Type: ITEM, ID: 103, Kind: Added},
+		{Type: ITEM, ID: 102, Kind: Removed},
+		{Type: ITEM, ID: 103, Kind: Added, Thing: NewThing(104, ITEM)},
+	}}
+	conflicts := patch.Apply(datfh)
+	if len(conflicts) != 3 {
+		t.Fatalf("got %d conflicts, want 3: %v", len(conflicts), conflicts)
+	}
+	if len(datfh.Items) != 3 {
+		t.Errorf("file has %d items, want 3", len(datfh.Items))
+	}
+}
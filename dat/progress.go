@@ -0,0 +1,170 @@
+package dat
+
+import "io"
+
+// Progress of deserialization. Done and Total count things of all
+// categories, CategoryDone and CategoryTotal count things of Category
+type Progress struct {
+	Category      string
+	CategoryDone  int
+	CategoryTotal int
+	Done          int
+	Total         int
+}
+
+// Observer is notified about progress of deserialization. Methods are called
+// synchronously by goroutine running deserialization
+type Observer interface {
+	// CategoryStarted is called before the first thing of category is
+	// decoded, also for empty categories
+	CategoryStarted(progress Progress)
+	// ThingDecoded is called after every decoded thing
+	ThingDecoded(thing *Thing, progress Progress)
+	// CategoryFinished is called after the last thing of category is decoded
+	CategoryFinished(progress Progress)
+}
+
+// ProgressFunc is Observer calling function after every decoded thing with
+// number of decoded things and total number of things in file
+type ProgressFunc func(done, total int)
+
+// CategoryStarted implements Observer interface
+func (fn ProgressFunc) CategoryStarted(progress Progress) {}
+
+// ThingDecoded implements Observer interface
+func (fn ProgressFunc) ThingDecoded(thing *Thing, progress Progress) {
+	fn(progress.Done, progress.Total)
+}
+
+// CategoryFinished implements Observer interface
+func (fn ProgressFunc) CategoryFinished(progress Progress) {}
+
+// nopObserver ignores all notifications
+type nopObserver struct{}
+
+func (nopObserver) CategoryStarted(progress Progress)            {}
+func (nopObserver) ThingDecoded(thing *Thing, progress Progress) {}
+func (nopObserver) CategoryFinished(progress Progress)           {}
+
+// cursor tracks position of the next thing to decode
+type cursor struct {
+	category int
+	id       int
+	started  bool
+	done     int
+}
+
+// Total returns number of things of all categories declared in file header
+func (datfh *File) Total() int {
+	total := 0
+	for _, typ := range typeNames {
+		first, end := datfh.idRange(typ)
+		total += end - first
+	}
+	return total
+}
+
+// idRange returns ID of the first thing of category and ID following the
+// last one, as declared in file header. Header declaring less than no things,
+// e.g. item count below 99, gives empty range
+func (datfh *File) idRange(typ string) (int, int) {
+	first, end := 1, datfh.missilesCount
+	switch typ {
+	case ITEM:
+		first, end = 100, datfh.itemsCount
+	case OUTFIT:
+		end = datfh.outfitsCount
+	case EFFECT:
+		end = datfh.effectsCount
+	}
+	if end < first {
+		end = first
+	}
+	return first, end
+}
+
+// DeserializeWith parses all remaining things of .dat file, notifying given
+// observer, which might be nil
+func (datfh *File) DeserializeWith(observer Observer) error {
+	_, err := datfh.DeserializeStep(0, observer)
+	return err
+}
+
+// DeserializeStep parses at most n things following ones parsed by previous
+// calls, or all remaining things if n <= 0, notifying given observer, which
+// might be nil. It returns true when all things are parsed. File can't be
+// resumed after an error
+func (datfh *File) DeserializeStep(n int, observer Observer) (bool, error) {
+	if observer == nil {
+		observer = nopObserver{}
+	}
+	c := datfh.codec()
+	decoded := 0
+	for datfh.advance(observer) {
+		if n > 0 && decoded >= n {
+			return false, nil
+		}
+		thing, err := datfh.decodeNext(c)
+		if err != nil {
+			return false, err
+		}
+		datfh.appendThing(thing)
+		decoded++
+		observer.ThingDecoded(thing, datfh.progress(thing.Type))
+	}
+	return true, nil
+}
+
+// Next parses thing following the last parsed one, without appending it to
+// Items, Outfits, Effects or Missiles, so whole file can be processed in
+// constant memory. Category and client ID of thing are its Type and ID. It
+// returns io.EOF after the last thing
+func (datfh *File) Next() (*Thing, error) {
+	if !datfh.advance(nopObserver{}) {
+		return nil, io.EOF
+	}
+	return datfh.decodeNext(datfh.codec())
+}
+
+// advance moves cursor past exhausted categories, notifying observer about
+// started and finished categories. It returns false if there are no things
+// left
+func (datfh *File) advance(observer Observer) bool {
+	for datfh.next.category < len(typeNames) {
+		typ := typeNames[datfh.next.category]
+		first, end := datfh.idRange(typ)
+		if !datfh.next.started {
+			datfh.next.started = true
+			datfh.next.id = first
+			observer.CategoryStarted(datfh.progress(typ))
+		}
+		if datfh.next.id < end {
+			return true
+		}
+		observer.CategoryFinished(datfh.progress(typ))
+		datfh.next = cursor{category: datfh.next.category + 1, done: datfh.next.done}
+	}
+	return false
+}
+
+// decodeNext parses thing at cursor, advance has to be called first
+func (datfh *File) decodeNext(c codec) (*Thing, error) {
+	thing, err := deserializeThing(uint16(datfh.next.id), typeNames[datfh.next.category], datfh, c)
+	if err != nil {
+		return nil, err
+	}
+	datfh.next.id++
+	datfh.next.done++
+	return thing, nil
+}
+
+func (datfh *File) progress(typ string) Progress {
+	first, end := datfh.idRange(typ)
+	return Progress{
+		Category:      typ,
+		CategoryDone:  datfh.next.id - first,
+		CategoryTotal: end - first,
+		Done:          datfh.next.done,
+		Total:         datfh.Total(),
+	}
+}
@@ -0,0 +1,43 @@
+package dat
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+func TestTotalSmallHeader(t *testing.T) {
+	// header of 50 items, no outfits, 2 effects and 1 missile, things
+	// themselves are missing
+	header := make([]byte, headerSize)
+	binary.LittleEndian.PutUint32(header, 0x4A10)
+	for i, count := range []uint16{50, 0, 2, 1} {
+		binary.LittleEndian.PutUint16(header[4+2*i:], count)
+	}
+	datfh, err := Open(writeTestFile(t, header))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total := datfh.Total(); total != 3 {
+		t.Errorf("Total() = %d, want 3", total)
+	}
+
+	started := &categoryRecorder{}
+	datfh.DeserializeWith(started)
+	if len(started.totals) == 0 || started.totals[0] != 0 {
+		t.Errorf("CategoryTotal of items %v, want 0", started.totals)
+	}
+
+	if total := (&File{}).Total(); total != 0 {
+		t.Errorf("Total() of zero File = %d, want 0", total)
+	}
+}
+
+// categoryRecorder records CategoryTotal of every started category
+type categoryRecorder struct {
+	nopObserver
+	totals []int
+}
+
+func (recorder *categoryRecorder) CategoryStarted(progress Progress) {
+	recorder.totals = append(recorder.totals, progress.CategoryTotal)
+}
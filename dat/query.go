@@ -0,0 +1,504 @@
+package dat
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Filter reports whether given thing matches some criteria
+type Filter func(thing *Thing) bool
+
+// Comparison operators accepted by Compare
+const (
+	EQ = "=="
+	NE = "!="
+	LT = "<"
+	LE = "<="
+	GT = ">"
+	GE = ">="
+)
+
+// spriteGroupFields maps field names usable in "sprite.<field>" expressions
+// to SpriteGroup struct fields
+var spriteGroupFields = map[string]string{
+	"group":          "Group",
+	"frameGroupType": "FrameGroupType",
+	"width":          "Width",
+	"height":         "Height",
+	"realSize":       "RealSize",
+	"layers":         "Layers",
+	"patternX":       "PatternXNum",
+	"patternY":       "PatternYNum",
+	"patternZ":       "PatternZNum",
+	"async":          "Async",
+	"startPhase":     "StartPhase",
+	"loopCount":      "LoopCount",
+}
+
+// HasAttribute matches things having attribute of given opcode
+func HasAttribute(op OpCode) Filter {
+	return func(thing *Thing) bool {
+		return thing.Has(op)
+	}
+}
+
+// And matches things matched by all given filters
+func And(filters ...Filter) Filter {
+	return func(thing *Thing) bool {
+		for _, f := range filters {
+			if !f(thing) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Or matches things matched by at least one of given filters
+func Or(filters ...Filter) Filter {
+	return func(thing *Thing) bool {
+		for _, f := range filters {
+			if f(thing) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Not matches things not matched by given filter
+func Not(f Filter) Filter {
+	return func(thing *Thing) bool {
+		return !f(thing)
+	}
+}
+
+// Compare matches things whose field compares to value using given operator.
+// Field is one of:
+// * id - thing ID
+// * type - thing type (item, outfit, effect, missile)
+// * <attribute>.<field> - attribute field by its XML name, e.g. light.color
+// * sprite.<field> - sprite group field, e.g. sprite.width; matches if any
+// of thing sprite groups matches; besides SpriteGroup fields "phases" and
+// "sprites" denote number of animation phases and sprites
+//
+// Fields of enum types, e.g. cloth.slot or market.category, accept names as
+// well as numbers
+func Compare(field, op, value string) (Filter, error) {
+	switch op {
+	case EQ, NE, LT, LE, GT, GE:
+	default:
+		return nil, fmt.Errorf("Unknown comparison operator: %q", op)
+	}
+	values, err := fieldGetter(field)
+	if err != nil {
+		return nil, err
+	}
+	return func(thing *Thing) bool {
+		for _, val := range values(thing) {
+			if compareValue(val, op, value) {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
+
+func fieldGetter(field string) (func(*Thing) []reflect.Value, error) {
+	switch field {
+	case "id":
+		return func(thing *Thing) []reflect.Value {
+			return []reflect.Value{reflect.ValueOf(thing.ID)}
+		}, nil
+	case "type":
+		return func(thing *Thing) []reflect.Value {
+			return []reflect.Value{reflect.ValueOf(thing.Type)}
+		}, nil
+	}
+
+	parts := strings.SplitN(field, ".", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("Unknown field: %q", field)
+	}
+	if parts[0] == "sprite" {
+		return spriteGroupGetter(parts[1])
+	}
+
+	op, ok := opCodeByName(parts[0])
+	if !ok {
+		return nil, fmt.Errorf("Unknown attribute: %q", parts[0])
+	}
+	if _, ok = attributeField(newAttribute(op), parts[1]); !ok {
+		return nil, fmt.Errorf("Attribute %s has no field %q", parts[0], parts[1])
+	}
+	return func(thing *Thing) []reflect.Value {
+		attr := thing.Attribute(op)
+		if attr == nil {
+			return nil
+		}
+		val, _ := attributeField(attr, parts[1])
+		return []reflect.Value{val}
+	}, nil
+}
+
+func spriteGroupGetter(name string) (func(*Thing) []reflect.Value, error) {
+	var get func(*SpriteGroup) reflect.Value
+
+	switch name {
+	case "phases":
+		get = func(sprGr *SpriteGroup) reflect.Value {
+			return reflect.ValueOf(len(sprGr.AnimationPhases))
+		}
+	case "sprites":
+		get = func(sprGr *SpriteGroup) reflect.Value {
+			return reflect.ValueOf(len(sprGr.Sprites))
+		}
+	default:
+		structField, ok := spriteGroupFields[name]
+		if !ok {
+			return nil, fmt.Errorf("Unknown sprite group field: %q", name)
+		}
+		get = func(sprGr *SpriteGroup) reflect.Value {
+			return reflect.ValueOf(sprGr).Elem().FieldByName(structField)
+		}
+	}
+	return func(thing *Thing) []reflect.Value {
+		values := make([]reflect.Value, 0, len(thing.SpriteGroups))
+		for _, sprGr := range thing.SpriteGroups {
+			values = append(values, get(sprGr))
+		}
+		return values
+	}, nil
+}
+
+// attributeField returns field of attribute by its XML attribute name
+func attributeField(attr Attribute, name string) (reflect.Value, bool) {
+	for _, field := range attributeFields(attr) {
+		if field.name == name {
+			return field.value, true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+type namedValue struct {
+	name  string
+	value reflect.Value
+}
+
+// attributeFields returns all data fields of attribute named by their XML
+// attribute names
+func attributeFields(attr Attribute) []namedValue {
+	val := reflect.Indirect(reflect.ValueOf(attr))
+	if val.Kind() != reflect.Struct {
+		return nil
+	}
+	typ := val.Type()
+	fields := make([]namedValue, 0, typ.NumField())
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.Anonymous {
+			continue
+		}
+		name := strings.Split(field.Tag.Get("xml"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+		fields = append(fields, namedValue{name, val.Field(i)})
+	}
+	return fields
+}
+
+func compareValue(val reflect.Value, op, value string) bool {
+	var cmp int
+
+	switch val.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		num, err := strconv.ParseUint(enumNumber(val, value), 0, 64)
+		if err != nil {
+			return false
+		}
+		cmp = compareOrdered(val.Uint(), num)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		num, err := strconv.ParseInt(value, 0, 64)
+		if err != nil {
+			return false
+		}
+		cmp = compareOrdered(val.Int(), num)
+	case reflect.String:
+		cmp = strings.Compare(val.String(), value)
+	default:
+		return false
+	}
+
+	switch op {
+	case EQ:
+		return cmp == 0
+	case NE:
+		return cmp != 0
+	case LT:
+		return cmp < 0
+	case LE:
+		return cmp <= 0
+	case GT:
+		return cmp > 0
+	case GE:
+		return cmp >= 0
+	}
+	return false
+}
+
+// enumNumber converts name of enum value to number, other values are
+// returned unchanged
+func enumNumber(val reflect.Value, value string) string {
+	if _, ok := val.Interface().(encoding.TextMarshaler); !ok {
+		return value
+	}
+	parsed := reflect.New(val.Type())
+	unmarshaler, ok := parsed.Interface().(encoding.TextUnmarshaler)
+	if !ok || unmarshaler.UnmarshalText([]byte(value)) != nil {
+		return value
+	}
+	return strconv.FormatUint(parsed.Elem().Uint(), 10)
+}
+
+func compareOrdered[T int64 | uint64](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// ParseFilter creates Filter from expression, e.g.
+//
+//	pickupable && !stackable && market.category == helmetsHats && light.intensity > 3
+//
+// Bare attribute name matches things having that attribute, comparisons
+// accept fields described in Compare. Terms might be combined with &&, ||, !
+// and grouped with parentheses. String values containing spaces or operator
+// characters have to be double quoted.
+func ParseFilter(expr string) (Filter, error) {
+	tokens, err := tokenize(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := &filterParser{tokens: tokens}
+	f, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("Unexpected token %q", p.tokens[p.pos].text)
+	}
+	return f, nil
+}
+
+const (
+	tokIdent = iota
+	tokValue
+	tokOp
+)
+
+type token struct {
+	kind int
+	text string
+}
+
+func tokenize(expr string) ([]token, error) {
+	tokens := make([]token, 0)
+	for i := 0; i < len(expr); {
+		c := rune(expr[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '"':
+			end := i + 1
+			for end < len(expr) && expr[end] != '"' {
+				if expr[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(expr) {
+				return nil, fmt.Errorf("Unterminated string at %d", i)
+			}
+			str, err := strconv.Unquote(expr[i : end+1])
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{tokValue, str})
+			i = end + 1
+		case strings.ContainsRune("&|!=<>()", c):
+			op := string(c)
+			if i+1 < len(expr) {
+				switch two := expr[i : i+2]; two {
+				case "&&", "||", EQ, NE, LE, GE:
+					op = two
+				}
+			}
+			if op == "&" || op == "|" || op == "=" {
+				return nil, fmt.Errorf("Unexpected %q at %d", op, i)
+			}
+			tokens = append(tokens, token{tokOp, op})
+			i += len(op)
+		default:
+			end := i
+			for end < len(expr) && !unicode.IsSpace(rune(expr[end])) &&
+				!strings.ContainsRune("&|!=<>()\"", rune(expr[end])) {
+				end++
+			}
+			tokens = append(tokens, token{tokIdent, expr[i:end]})
+			i = end
+		}
+	}
+	return tokens, nil
+}
+
+type filterParser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *filterParser) peek() *token {
+	if p.pos < len(p.tokens) {
+		return &p.tokens[p.pos]
+	}
+	return nil
+}
+
+func (p *filterParser) acceptOp(op string) bool {
+	if tok := p.peek(); tok != nil && tok.kind == tokOp && tok.text == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *filterParser) parseOr() (Filter, error) {
+	f, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	filters := []Filter{f}
+	for p.acceptOp("||") {
+		if f, err = p.parseAnd(); err != nil {
+			return nil, err
+		}
+		filters = append(filters, f)
+	}
+	if len(filters) == 1 {
+		return filters[0], nil
+	}
+	return Or(filters...), nil
+}
+
+func (p *filterParser) parseAnd() (Filter, error) {
+	f, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	filters := []Filter{f}
+	for p.acceptOp("&&") {
+		if f, err = p.parseUnary(); err != nil {
+			return nil, err
+		}
+		filters = append(filters, f)
+	}
+	if len(filters) == 1 {
+		return filters[0], nil
+	}
+	return And(filters...), nil
+}
+
+func (p *filterParser) parseUnary() (Filter, error) {
+	if p.acceptOp("!") {
+		f, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return Not(f), nil
+	}
+	if p.acceptOp("(") {
+		f, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.acceptOp(")") {
+			return nil, fmt.Errorf("Missing closing parenthesis")
+		}
+		return f, nil
+	}
+	return p.parseTerm()
+}
+
+func (p *filterParser) parseTerm() (Filter, error) {
+	tok := p.peek()
+	if tok == nil {
+		return nil, fmt.Errorf("Unexpected end of expression")
+	}
+	if tok.kind != tokIdent {
+		return nil, fmt.Errorf("Unexpected token %q", tok.text)
+	}
+	p.pos++
+	field := tok.text
+
+	if op := p.peek(); op != nil && op.kind == tokOp {
+		switch op.text {
+		case EQ, NE, LT, LE, GT, GE:
+			p.pos++
+			value := p.peek()
+			if value == nil || value.kind == tokOp {
+				return nil, fmt.Errorf("Missing value after %s %s", field, op.text)
+			}
+			p.pos++
+			return Compare(field, op.text, value.text)
+		}
+	}
+
+	attrOp, ok := opCodeByName(field)
+	if !ok {
+		return nil, fmt.Errorf("Unknown attribute: %q", field)
+	}
+	return HasAttribute(attrOp), nil
+}
+
+// Find returns all things of every category matching given filter
+func (datfh *File) Find(f Filter) []*Thing {
+	found := make([]*Thing, 0)
+	for _, things := range [][]*Thing{datfh.Items, datfh.Outfits, datfh.Effects, datfh.Missiles} {
+		found = appendMatching(found, things, f)
+	}
+	return found
+}
+
+// FindIn returns things of given category (item, outfit, effect, missile)
+// matching given filter
+func (datfh *File) FindIn(category string, f Filter) []*Thing {
+	return appendMatching(make([]*Thing, 0), datfh.Category(category), f)
+}
+
+// Query parses given expression (see ParseFilter) and returns all matching
+// things
+func (datfh *File) Query(expr string) ([]*Thing, error) {
+	f, err := ParseFilter(expr)
+	if err != nil {
+		return nil, err
+	}
+	return datfh.Find(f), nil
+}
+
+func appendMatching(found, things []*Thing, f Filter) []*Thing {
+	for _, thing := range things {
+		if f(thing) {
+			found = append(found, thing)
+		}
+	}
+	return found
+}
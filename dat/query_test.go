@@ -0,0 +1,101 @@
+package dat
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+// queryTestFile returns file of things matching some of the test queries
+func queryTestFile() *File {
+	datfh := &File{}
+	things := []*Thing{
+		{ID: 100, Type: ITEM, Attributes: []Attribute{NewPickupable(),
+			NewMarket(7, 100, 100, "torch", 0, 0), NewLight(4, 206)}},
+		{ID: 101, Type: ITEM, Attributes: []Attribute{NewPickupable(), NewStackable(),
+			NewMarket(7, 101, 101, "gold coin", 0, 0), NewLight(4, 206)}},
+		{ID: 102, Type: ITEM, Attributes: []Attribute{NewPickupable(),
+			NewMarket(7, 102, 102, "candle", 0, 0), NewLight(2, 206)}},
+		{ID: 103, Type: ITEM, Attributes: []Attribute{NewPickupable(),
+			NewMarket(3, 103, 103, "lamp", 0, 0), NewLight(7, 206)}},
+		{ID: 104, Type: ITEM, Attributes: []Attribute{NewGround(150)}},
+		{ID: 1, Type: OUTFIT, SpriteGroups: []*SpriteGroup{
+			{Width: 1, Height: 1}, {Width: 2, Height: 2}}},
+		{ID: 1, Type: EFFECT, Attributes: []Attribute{NewTopEffect()},
+			SpriteGroups: []*SpriteGroup{{Width: 1, Height: 1}}},
+	}
+	for _, thing := range things {
+		datfh.AppendThing(thing)
+	}
+	return datfh
+}
+
+// thingIDs returns types and IDs of things
+func thingIDs(things []*Thing) []string {
+	ids := make([]string, 0, len(things))
+	for _, thing := range things {
+		ids = append(ids, thing.Type+" "+strconv.Itoa(int(thing.ID)))
+	}
+	return ids
+}
+
+func TestQuery(t *testing.T) {
+	tests := []struct {
+		expr string
+		want []string
+	}{
+		// example of feature request
+		{"pickupable && !stackable && market.category == 7 && light.intensity > 3",
+			[]string{"item 100"}},
+		{"ground", []string{"item 104"}},
+		{"ground.val >= 150 && type == item", []string{"item 104"}},
+		{`market.itemName == "gold coin"`, []string{"item 101"}},
+		{"sprite.width == 2", []string{"outfit 1"}},
+		{"sprite.width == 1 && sprite.height == 1", []string{"outfit 1", "effect 1"}},
+		{"sprite.width > 2", nil},
+		{"id == 1", []string{"outfit 1", "effect 1"}},
+		{"id >= 0x66 && id < 104", []string{"item 102", "item 103"}},
+		// && binds tighter than ||, ! tighter than &&
+		{"ground || stackable && light", []string{"item 101", "item 104"}},
+		{"(ground || stackable) && light", []string{"item 101"}},
+		{"!pickupable && !topEffect", []string{"item 104", "outfit 1"}},
+		{"!(pickupable || topEffect)", []string{"item 104", "outfit 1"}},
+		{"!stackable && light.intensity == 4 || ground", []string{"item 100", "item 104"}},
+	}
+
+	datfh := queryTestFile()
+	for _, test := range tests {
+		things, err := datfh.Query(test.expr)
+		if err != nil {
+			t.Errorf("%s: %v", test.expr, err)
+			continue
+		}
+		if got := thingIDs(things); !reflect.DeepEqual(got, append([]string{}, test.want...)) {
+			t.Errorf("%s: got %v, want %v", test.expr, got, test.want)
+		}
+	}
+}
+
+func TestFindIn(t *testing.T) {
+	f, err := ParseFilter("sprite.width == 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := thingIDs(queryTestFile().FindIn(EFFECT, f)); !reflect.DeepEqual(got, []string{"effect 1"}) {
+		t.Errorf("got %v, want [effect 1]", got)
+	}
+}
+
+func TestParseFilterErrors(t *testing.T) {
+	exprs := []string{
+		"", "end", "has(end)", "end.val == 1", "pickupable && end",
+		"unknown", "light.unknown > 1", "sprite.unknown == 1", "light.intensity >",
+		"(pickupable", "pickupable)", "pickupable &&", "light.intensity ~ 3",
+		`market.name == "gold coin"`,
+	}
+	for _, expr := range exprs {
+		if _, err := ParseFilter(expr); err == nil {
+			t.Errorf("ParseFilter(%q) succeeded, want error", expr)
+		}
+	}
+}
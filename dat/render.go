@@ -0,0 +1,89 @@
+package dat
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+)
+
+// SpriteSource provides sprite images by their IDs, e.g. spr.File
+type SpriteSource interface {
+	GetSprite(id int) (*image.RGBA, error)
+}
+
+// RenderOptions selects which sprites of thing are rendered. Group is 0-based
+// index of thing sprite group, Layers lists layers to draw, all layers are
+// drawn if it's nil
+type RenderOptions struct {
+	Group    int
+	PatternX int
+	PatternY int
+	PatternZ int
+	Frame    int
+	Layers   []int
+}
+
+// Render composes tiles and layers of thing sprite group into single image.
+// Size of tile is taken from sprites returned by given source. Tiles are laid
+// out from bottom right corner, image is big enough to hold Width x Height
+// tiles and RealSize, and thing Displacement shifts tiles up and left
+func Render(thing *Thing, sprites SpriteSource, opts RenderOptions) (*image.RGBA, error) {
+	if opts.Group < 0 || opts.Group >= len(thing.SpriteGroups) {
+		return nil, fmt.Errorf("%s %d has no sprite group %d", thing.Type, thing.ID, opts.Group)
+	}
+	sprGr := thing.SpriteGroups[opts.Group]
+
+	layers := opts.Layers
+	if layers == nil {
+		layers = make([]int, sprGr.Layers)
+		for l := range layers {
+			layers[l] = l
+		}
+	}
+
+	var dx, dy int
+	if displacement, ok := Get[*Displacement](thing); ok {
+		dx, dy = int(displacement.X), int(displacement.Y)
+	}
+
+	var canvas *image.RGBA
+	for _, layer := range layers {
+		for h := 0; h < int(sprGr.Height); h++ {
+			for w := 0; w < int(sprGr.Width); w++ {
+				sprID, err := sprGr.SpriteID(w, h, layer, opts.PatternX, opts.PatternY,
+					opts.PatternZ, opts.Frame)
+				if err != nil {
+					return nil, err
+				}
+				sprite, err := sprites.GetSprite(int(sprID))
+				if err != nil {
+					return nil, err
+				}
+				if canvas == nil {
+					canvas = newCanvas(sprGr, sprite.Bounds().Dx(), dx, dy)
+				}
+				size := sprite.Bounds().Dx()
+				x := canvas.Rect.Dx() - (w+1)*size - dx
+				y := canvas.Rect.Dy() - (h+1)*size - dy
+				dest := image.Rect(x, y, x+size, y+sprite.Bounds().Dy())
+				draw.Draw(canvas, dest, sprite, sprite.Bounds().Min, draw.Over)
+			}
+		}
+	}
+	if canvas == nil {
+		canvas = newCanvas(sprGr, 32, dx, dy)
+	}
+	return canvas, nil
+}
+
+func newCanvas(sprGr *SpriteGroup, tileSize, dx, dy int) *image.RGBA {
+	width := int(sprGr.Width) * tileSize
+	height := int(sprGr.Height) * tileSize
+	if int(sprGr.RealSize) > width {
+		width = int(sprGr.RealSize)
+	}
+	if int(sprGr.RealSize) > height {
+		height = int(sprGr.RealSize)
+	}
+	return image.NewRGBA(image.Rect(0, 0, width+dx, height+dy))
+}
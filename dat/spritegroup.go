@@ -6,92 +6,160 @@ import (
 	bin "github.com/go-otserv/encoding/binary"
 )
 
+// Frame group types of outfit sprite groups
+const (
+	FrameGroupIdle   uint8 = 0
+	FrameGroupMoving uint8 = 1
+)
+
 // SpriteGroup holds information about single group of sprites. One item might
 // have one or more SpriteGroup assigned
 type SpriteGroup struct {
-	Group           int
-	FrameGroupType  uint8
-	Width           uint8
-	Height          uint8
-	RealSize        uint8
-	Layers          uint8
-	PatternXNum     uint8
-	PatternYNum     uint8
-	PatternZNum     uint8
-	Async           uint8
-	StartPhase      uint8
-	LoopCount       uint32
-	AnimationPhases []*AnimationPhase
-	Sprites         []uint32
+	Group           int               `xml:"group,attr" json:"group"`
+	FrameGroupType  uint8             `xml:"frameGroupType,attr" json:"frameGroupType"`
+	Width           uint8             `xml:"width,attr" json:"width"`
+	Height          uint8             `xml:"height,attr" json:"height"`
+	RealSize        uint8             `xml:"realSize,attr" json:"realSize"`
+	Layers          uint8             `xml:"layers,attr" json:"layers"`
+	PatternXNum     uint8             `xml:"patternX,attr" json:"patternX"`
+	PatternYNum     uint8             `xml:"patternY,attr" json:"patternY"`
+	PatternZNum     uint8             `xml:"patternZ,attr" json:"patternZ"`
+	Async           uint8             `xml:"async,attr" json:"async"`
+	StartPhase      uint8             `xml:"startPhase,attr" json:"startPhase"`
+	LoopCount       uint32            `xml:"loopCount,attr" json:"loopCount"`
+	AnimationPhases []*AnimationPhase `xml:"phase" json:"animationPhases"`
+	Sprites         []uint32          `xml:"-" json:"sprites"`
 }
 
 // AnimationPhase holds information about animation phase of SpriteGroup. One
 // SpriteGroup might have many animation phases
 type AnimationPhase struct {
-	FrameA uint32
-	FrameB uint32
+	FrameA uint32 `xml:"frameA,attr" json:"frameA"`
+	FrameB uint32 `xml:"frameB,attr" json:"frameB"`
+}
+
+// NewSpriteGroup creates sprite group of single blank sprite, with size, layers,
+// patterns and animation phases all set to one
+func NewSpriteGroup() *SpriteGroup {
+	return &SpriteGroup{
+		Group:           1,
+		Width:           1,
+		Height:          1,
+		Layers:          1,
+		PatternXNum:     1,
+		PatternYNum:     1,
+		PatternZNum:     1,
+		AnimationPhases: make([]*AnimationPhase, 0),
+		Sprites:         []uint32{0},
+	}
+}
+
+// Clone returns deep copy of sprite group
+func (sprGr *SpriteGroup) Clone() *SpriteGroup {
+	clone := *sprGr
+	clone.AnimationPhases = make([]*AnimationPhase, 0, len(sprGr.AnimationPhases))
+	for _, animPhase := range sprGr.AnimationPhases {
+		phase := *animPhase
+		clone.AnimationPhases = append(clone.AnimationPhases, &phase)
+	}
+	if sprGr.Sprites != nil {
+		clone.Sprites = append(make([]uint32, 0, len(sprGr.Sprites)), sprGr.Sprites...)
+	}
+	return &clone
 }
 
-func deserializeSpriteGroup(typ string, datfh bin.Reader) (*SpriteGroup, error) {
+func deserializeSpriteGroup(typ string, datfh bin.Reader, features Features) (*SpriteGroup, error) {
 	var err error
 	var sprID uint32
+	var shortID uint16
+
+	sprGr, sprCount, err := deserializeSpriteGroupInfo(typ, datfh, features)
+	if err != nil {
+		return nil, err
+	}
+	for sprNum := 0; sprNum < sprCount; sprNum++ {
+		if features.Extended {
+			if sprID, err = datfh.UInt32(); err != nil {
+				return nil, err
+			}
+		} else {
+			if shortID, err = datfh.UInt16(); err != nil {
+				return nil, err
+			}
+			sprID = uint32(shortID)
+		}
+		sprGr.Sprites = append(sprGr.Sprites, sprID)
+	}
+	return sprGr, nil
+}
+
+// deserializeSpriteGroupInfo reads sprite group up to its sprite IDs and
+// returns number of sprites which follow. Without improved animations only
+// number of animation phases is stored, their durations are set to defaults
+func deserializeSpriteGroupInfo(typ string, datfh bin.Reader, features Features) (*SpriteGroup, int, error) {
+	var err error
 	var animPhases uint8
 
 	sprGr := &SpriteGroup{}
 
-	if typ == OUTFIT {
+	if typ == OUTFIT && features.FrameGroups {
 		if sprGr.FrameGroupType, err = datfh.UInt8(); err != nil {
-			return nil, err
+			return nil, 0, err
 		}
 	}
 
 	if sprGr.Width, err = datfh.UInt8(); err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	if sprGr.Height, err = datfh.UInt8(); err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	if sprGr.Width > 1 || sprGr.Height > 1 {
 		if sprGr.RealSize, err = datfh.UInt8(); err != nil {
-			return nil, err
+			return nil, 0, err
 		}
 	}
 
 	if sprGr.Layers, err = datfh.UInt8(); err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	if sprGr.PatternXNum, err = datfh.UInt8(); err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	if sprGr.PatternYNum, err = datfh.UInt8(); err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	if sprGr.PatternZNum, err = datfh.UInt8(); err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	if animPhases, err = datfh.UInt8(); err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
 	sprGr.AnimationPhases = make([]*AnimationPhase, 0, animPhases-1)
-	if animPhases > 1 {
+	if animPhases > 1 && !features.ImprovedAnimations {
+		for phase := 0; phase < int(animPhases); phase++ {
+			sprGr.AnimationPhases = append(sprGr.AnimationPhases, &AnimationPhase{})
+		}
+		defaultPhaseDurations(typ, sprGr)
+	} else if animPhases > 1 {
 		if sprGr.Async, err = datfh.UInt8(); err != nil {
-			return nil, err
+			return nil, 0, err
 		}
 		if sprGr.StartPhase, err = datfh.UInt8(); err != nil {
-			return nil, err
+			return nil, 0, err
 		}
 		if sprGr.LoopCount, err = datfh.UInt32(); err != nil {
-			return nil, err
+			return nil, 0, err
 		}
 
 		for phase := 0; phase < int(animPhases); phase++ {
 			animPhase := &AnimationPhase{}
 			if animPhase.FrameA, err = datfh.UInt32(); err != nil {
-				return nil, err
+				return nil, 0, err
 			}
 			if animPhase.FrameB, err = datfh.UInt32(); err != nil {
-				return nil, err
+				return nil, 0, err
 			}
 			sprGr.AnimationPhases = append(sprGr.AnimationPhases, animPhase)
 		}
@@ -101,14 +169,187 @@ func deserializeSpriteGroup(typ string, datfh bin.Reader) (*SpriteGroup, error)
 		int(sprGr.PatternXNum) * int(sprGr.PatternYNum) * int(sprGr.PatternZNum) *
 		int(animPhases)
 	if sprCount > 4096 {
-		return nil, fmt.Errorf("sprites count for item > 4096")
+		return nil, 0, fmt.Errorf("sprites count for item > 4096")
+	}
+	return sprGr, sprCount, nil
+}
+
+// SpriteCoords locates sprite within SpriteGroup: tile (W, H counted from
+// bottom right corner), layer, pattern and animation frame
+type SpriteCoords struct {
+	W        int
+	H        int
+	Layer    int
+	PatternX int
+	PatternY int
+	PatternZ int
+	Frame    int
+}
+
+// SpriteIndex returns index in Sprites of sprite at given coordinates, using
+// the same layout as the client: width varies fastest, followed by height,
+// layer, pattern X, Y, Z and animation frame. Frame wraps around number of
+// animation phases. -1 is returned for coordinates out of range
+func (sprGr *SpriteGroup) SpriteIndex(w, h, layer, px, py, pz, frame int) int {
+	phases := sprGr.phasesCount()
+	if phases == 0 || frame < 0 ||
+		!inRange(w, sprGr.Width) || !inRange(h, sprGr.Height) ||
+		!inRange(layer, sprGr.Layers) || !inRange(px, sprGr.PatternXNum) ||
+		!inRange(py, sprGr.PatternYNum) || !inRange(pz, sprGr.PatternZNum) {
+		return -1
 	}
+	idx := frame % phases
+	idx = idx*int(sprGr.PatternZNum) + pz
+	idx = idx*int(sprGr.PatternYNum) + py
+	idx = idx*int(sprGr.PatternXNum) + px
+	idx = idx*int(sprGr.Layers) + layer
+	idx = idx*int(sprGr.Height) + h
+	return idx*int(sprGr.Width) + w
+}
 
-	for sprNum := 0; sprNum < sprCount; sprNum++ {
-		if sprID, err = datfh.UInt32(); err != nil {
-			return nil, err
+// SpriteID returns ID of sprite at given coordinates, see SpriteIndex
+func (sprGr *SpriteGroup) SpriteID(w, h, layer, px, py, pz, frame int) (uint32, error) {
+	idx := sprGr.SpriteIndex(w, h, layer, px, py, pz, frame)
+	if idx < 0 || idx >= len(sprGr.Sprites) {
+		return 0, fmt.Errorf("No sprite at w=%d h=%d layer=%d pattern=%d,%d,%d frame=%d",
+			w, h, layer, px, py, pz, frame)
+	}
+	return sprGr.Sprites[idx], nil
+}
+
+// EachSprite calls fn for every sprite of group in order of Sprites, passing
+// its coordinates and ID. Iteration stops when fn returns false
+func (sprGr *SpriteGroup) EachSprite(fn func(coords SpriteCoords, sprID uint32) bool) {
+	dims := []int{
+		int(sprGr.Width), int(sprGr.Height), int(sprGr.Layers),
+		int(sprGr.PatternXNum), int(sprGr.PatternYNum), int(sprGr.PatternZNum),
+	}
+	for _, dim := range dims {
+		if dim == 0 {
+			return
 		}
-		sprGr.Sprites = append(sprGr.Sprites, sprID)
 	}
-	return sprGr, nil
+	for idx, sprID := range sprGr.Sprites {
+		rest := idx
+		coords := make([]int, len(dims))
+		for i, dim := range dims {
+			coords[i] = rest % dim
+			rest /= dim
+		}
+		c := SpriteCoords{coords[0], coords[1], coords[2], coords[3], coords[4], coords[5], rest}
+		if !fn(c, sprID) {
+			return
+		}
+	}
+}
+
+func inRange(val int, size uint8) bool {
+	return val >= 0 && val < int(size)
+}
+
+// phasesCount returns number of animation phases as stored in .dat file
+func (sprGr *SpriteGroup) phasesCount() int {
+	if len(sprGr.AnimationPhases) > 0 {
+		return len(sprGr.AnimationPhases)
+	}
+	if len(sprGr.Sprites) == 0 && sprGr.Width > 0 && sprGr.Height > 0 &&
+		sprGr.Layers > 0 && sprGr.PatternXNum > 0 && sprGr.PatternYNum > 0 &&
+		sprGr.PatternZNum > 0 {
+		return 0
+	}
+	return 1
+}
+
+func (sprGr *SpriteGroup) serialize(typ string, datfh bin.Writer, features Features) error {
+	if err := sprGr.serializeInfo(typ, datfh, features); err != nil {
+		return err
+	}
+	for _, sprID := range sprGr.Sprites {
+		var err error
+		if features.Extended {
+			err = datfh.PutUInt32(sprID)
+		} else if sprID > 0xFFFF {
+			err = fmt.Errorf("Sprite ID %d exceeds 65535, extended format is required", sprID)
+		} else {
+			err = datfh.PutUInt16(uint16(sprID))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// serializeInfo writes sprite group up to its sprite IDs, durations of
+// animation phases are omitted without improved animations
+func (sprGr *SpriteGroup) serializeInfo(typ string, datfh bin.Writer, features Features) error {
+	var err error
+
+	animPhases := sprGr.phasesCount()
+	if animPhases > 255 {
+		return fmt.Errorf("Too many animation phases: %d", animPhases)
+	}
+	sprCount := int(sprGr.Width) * int(sprGr.Height) * int(sprGr.Layers) *
+		int(sprGr.PatternXNum) * int(sprGr.PatternYNum) * int(sprGr.PatternZNum) *
+		animPhases
+	if sprCount != len(sprGr.Sprites) {
+		return fmt.Errorf("Expected %d sprites in sprite group, got %d",
+			sprCount, len(sprGr.Sprites))
+	}
+
+	if typ == OUTFIT && features.FrameGroups {
+		if err = datfh.PutUInt8(sprGr.FrameGroupType); err != nil {
+			return err
+		}
+	}
+
+	if err = datfh.PutUInt8(sprGr.Width); err != nil {
+		return err
+	}
+	if err = datfh.PutUInt8(sprGr.Height); err != nil {
+		return err
+	}
+	if sprGr.Width > 1 || sprGr.Height > 1 {
+		if err = datfh.PutUInt8(sprGr.RealSize); err != nil {
+			return err
+		}
+	}
+
+	if err = datfh.PutUInt8(sprGr.Layers); err != nil {
+		return err
+	}
+	if err = datfh.PutUInt8(sprGr.PatternXNum); err != nil {
+		return err
+	}
+	if err = datfh.PutUInt8(sprGr.PatternYNum); err != nil {
+		return err
+	}
+	if err = datfh.PutUInt8(sprGr.PatternZNum); err != nil {
+		return err
+	}
+	if err = datfh.PutUInt8(uint8(animPhases)); err != nil {
+		return err
+	}
+
+	if animPhases > 1 && features.ImprovedAnimations {
+		if err = datfh.PutUInt8(sprGr.Async); err != nil {
+			return err
+		}
+		if err = datfh.PutUInt8(sprGr.StartPhase); err != nil {
+			return err
+		}
+		if err = datfh.PutUInt32(sprGr.LoopCount); err != nil {
+			return err
+		}
+
+		for _, animPhase := range sprGr.AnimationPhases {
+			if err = datfh.PutUInt32(animPhase.FrameA); err != nil {
+				return err
+			}
+			if err = datfh.PutUInt32(animPhase.FrameB); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
 }
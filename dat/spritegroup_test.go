@@ -0,0 +1,85 @@
+package dat
+
+import "testing"
+
+// testSpriteGroup returns 2x2 group of 2 layers, 4x2x2 patterns and 3
+// animation phases, sprite at index i has ID i+1
+func testSpriteGroup() *SpriteGroup {
+	sprGr := &SpriteGroup{
+		Width: 2, Height: 2, Layers: 2,
+		PatternXNum: 4, PatternYNum: 2, PatternZNum: 2,
+		AnimationPhases: []*AnimationPhase{{}, {}, {}},
+	}
+	for i := 0; i < 2*2*2*4*2*2*3; i++ {
+		sprGr.Sprites = append(sprGr.Sprites, uint32(i+1))
+	}
+	return sprGr
+}
+
+func TestSpriteIndex(t *testing.T) {
+	// indices computed by hand from client layout
+	// ((((((frame*pz+z)*py+y)*px+x)*layers+l)*h+hh)*w+ww)
+	tests := []struct {
+		coords SpriteCoords
+		want   int
+	}{
+		{SpriteCoords{0, 0, 0, 0, 0, 0, 0}, 0},
+		{SpriteCoords{1, 0, 0, 0, 0, 0, 0}, 1},
+		{SpriteCoords{0, 1, 0, 0, 0, 0, 0}, 2},
+		{SpriteCoords{0, 0, 1, 0, 0, 0, 0}, 4},
+		{SpriteCoords{0, 0, 0, 1, 0, 0, 0}, 8},
+		{SpriteCoords{0, 0, 0, 0, 1, 0, 0}, 32},
+		{SpriteCoords{0, 0, 0, 0, 0, 1, 0}, 64},
+		{SpriteCoords{0, 0, 0, 0, 0, 0, 1}, 128},
+		{SpriteCoords{1, 0, 1, 2, 1, 0, 1}, 181},
+		{SpriteCoords{0, 1, 0, 3, 0, 1, 2}, 346},
+		{SpriteCoords{1, 1, 1, 3, 1, 1, 2}, 383},
+		// frame wraps around number of phases
+		{SpriteCoords{1, 0, 1, 2, 1, 0, 4}, 181},
+		{SpriteCoords{2, 0, 0, 0, 0, 0, 0}, -1},
+		{SpriteCoords{0, 0, 0, 4, 0, 0, 0}, -1},
+		{SpriteCoords{0, 0, 0, 0, 0, 0, -1}, -1},
+	}
+
+	sprGr := testSpriteGroup()
+	for _, test := range tests {
+		c := test.coords
+		got := sprGr.SpriteIndex(c.W, c.H, c.Layer, c.PatternX, c.PatternY, c.PatternZ, c.Frame)
+		if got != test.want {
+			t.Errorf("SpriteIndex(%+v) = %d, want %d", c, got, test.want)
+		}
+		sprID, err := sprGr.SpriteID(c.W, c.H, c.Layer, c.PatternX, c.PatternY, c.PatternZ, c.Frame)
+		switch {
+		case test.want < 0 && err == nil:
+			t.Errorf("SpriteID(%+v) = %d, want error", c, sprID)
+		case test.want >= 0 && (err != nil || sprID != uint32(test.want+1)):
+			t.Errorf("SpriteID(%+v) = %d, %v, want %d", c, sprID, err, test.want+1)
+		}
+	}
+}
+
+func TestEachSprite(t *testing.T) {
+	sprGr := testSpriteGroup()
+	count := 0
+	sprGr.EachSprite(func(c SpriteCoords, sprID uint32) bool {
+		idx := sprGr.SpriteIndex(c.W, c.H, c.Layer, c.PatternX, c.PatternY, c.PatternZ, c.Frame)
+		if idx != count || sprID != uint32(count+1) {
+			t.Fatalf("sprite %d at %+v has index %d and ID %d", count, c, idx, sprID)
+		}
+		count++
+		return true
+	})
+	if count != len(sprGr.Sprites) {
+		t.Errorf("EachSprite visited %d sprites, want %d", count, len(sprGr.Sprites))
+	}
+
+	want := SpriteCoords{1, 1, 1, 3, 1, 1, 2}
+	var last SpriteCoords
+	sprGr.EachSprite(func(c SpriteCoords, sprID uint32) bool {
+		last = c
+		return sprID != 384
+	})
+	if last != want {
+		t.Errorf("last sprite at %+v, want %+v", last, want)
+	}
+}
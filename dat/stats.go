@@ -0,0 +1,185 @@
+package dat
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FileStats holds statistics of File, see Stats
+type FileStats struct {
+	Categories       []*CategoryStats `json:"categories"`
+	Attributes       []*Count         `json:"attributes"`
+	SpriteSizes      []*Count         `json:"spriteSizes"`
+	AnimationPhases  []*Count         `json:"animationPhases"`
+	MarketCategories []*Count         `json:"marketCategories"`
+	UniqueSprites    int              `json:"uniqueSprites"`
+}
+
+// CategoryStats holds numbers of things and sprite groups of single type
+type CategoryStats struct {
+	Category     string `json:"category"`
+	Things       int    `json:"things"`
+	SpriteGroups int    `json:"spriteGroups"`
+}
+
+// Count is single bucket of histogram
+type Count struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// histogram counts occurrences of keys, which are sorted by order
+type histogram struct {
+	counts map[int]int
+	names  map[int]string
+}
+
+func newHistogram() *histogram {
+	return &histogram{counts: make(map[int]int), names: make(map[int]string)}
+}
+
+func (h *histogram) add(order int, name string) {
+	h.counts[order]++
+	h.names[order] = name
+}
+
+func (h *histogram) list() []*Count {
+	orders := make([]int, 0, len(h.counts))
+	for order := range h.counts {
+		orders = append(orders, order)
+	}
+	sort.Ints(orders)
+	list := make([]*Count, 0, len(orders))
+	for _, order := range orders {
+		list = append(list, &Count{h.names[order], h.counts[order]})
+	}
+	return list
+}
+
+// Stats computes statistics of all things of file: numbers of things per
+// category, number of things having each attribute, sprite group sizes and
+// numbers of animation phases, number of unique referenced sprite IDs and
+// number of items in each market category. Histograms are ordered by
+// opcode, size, number of phases and category value
+func Stats(datfh *File) *FileStats {
+	stats := &FileStats{Categories: make([]*CategoryStats, 0, len(typeNames))}
+	attrs, sizes, phases, market := newHistogram(), newHistogram(), newHistogram(), newHistogram()
+	sprites := make(map[uint32]bool)
+
+	for _, typ := range typeNames {
+		cat := &CategoryStats{Category: typ}
+		for _, thing := range datfh.Category(typ) {
+			cat.Things++
+			cat.SpriteGroups += len(thing.SpriteGroups)
+			var seen Flags
+			for _, attr := range thing.Attributes {
+				if op := attr.OpCode(); !seen.Has(op) {
+					seen.Set(op)
+					attrs.add(int(op), op.String())
+				}
+			}
+			if m, ok := Get[*Market](thing); ok {
+				market.add(int(m.Category), m.Category.String())
+			}
+			for _, sprGr := range thing.SpriteGroups {
+				sizes.add(int(sprGr.Width)<<8|int(sprGr.Height),
+					fmt.Sprintf("%dx%d", sprGr.Width, sprGr.Height))
+				phases.add(sprGr.phasesCount(), strconv.Itoa(sprGr.phasesCount()))
+				for _, sprID := range sprGr.Sprites {
+					if sprID != 0 {
+						sprites[sprID] = true
+					}
+				}
+			}
+		}
+		stats.Categories = append(stats.Categories, cat)
+	}
+
+	stats.Attributes = attrs.list()
+	stats.SpriteSizes = sizes.list()
+	stats.AnimationPhases = phases.list()
+	stats.MarketCategories = market.list()
+	stats.UniqueSprites = len(sprites)
+	return stats
+}
+
+// statsSection is titled histogram of FileStats
+type statsSection struct {
+	title  string
+	counts []*Count
+}
+
+// sections returns histograms in order of reporting
+func (stats *FileStats) sections() []statsSection {
+	return []statsSection{
+		{"Attributes", stats.Attributes},
+		{"Sprite sizes", stats.SpriteSizes},
+		{"Animation phases", stats.AnimationPhases},
+		{"Market categories", stats.MarketCategories},
+	}
+}
+
+// WriteJSON writes statistics as JSON document
+func (stats *FileStats) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(stats)
+}
+
+// WriteText writes statistics in human readable form
+func (stats *FileStats) WriteText(w io.Writer) error {
+	for _, cat := range stats.Categories {
+		_, err := fmt.Fprintf(w, "%s: %d things, %d sprite groups\n",
+			cat.Category, cat.Things, cat.SpriteGroups)
+		if err != nil {
+			return err
+		}
+	}
+	if _, err := fmt.Fprintf(w, "unique sprites: %d\n", stats.UniqueSprites); err != nil {
+		return err
+	}
+	for _, section := range stats.sections() {
+		if _, err := fmt.Fprintf(w, "%s:\n", strings.ToLower(section.title)); err != nil {
+			return err
+		}
+		for _, count := range section.counts {
+			if _, err := fmt.Fprintf(w, "    %s: %d\n", count.Name, count.Count); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// WriteMarkdown writes statistics as Markdown document of tables
+func (stats *FileStats) WriteMarkdown(w io.Writer) error {
+	_, err := fmt.Fprint(w, "## Categories\n\n| Category | Things | Sprite groups |\n| --- | ---: | ---: |\n")
+	if err != nil {
+		return err
+	}
+	for _, cat := range stats.Categories {
+		_, err = fmt.Fprintf(w, "| %s | %d | %d |\n", cat.Category, cat.Things, cat.SpriteGroups)
+		if err != nil {
+			return err
+		}
+	}
+	if _, err = fmt.Fprintf(w, "\nUnique sprites: %d\n", stats.UniqueSprites); err != nil {
+		return err
+	}
+	for _, section := range stats.sections() {
+		_, err = fmt.Fprintf(w, "\n## %s\n\n| Value | Count |\n| --- | ---: |\n", section.title)
+		if err != nil {
+			return err
+		}
+		for _, count := range section.counts {
+			if _, err = fmt.Fprintf(w, "| %s | %d |\n", count.Name, count.Count); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package dat
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// statsTestFile returns file of 2 items, outfit and effect
+func statsTestFile() *File {
+	datfh := NewFile(0)
+	phases := func(n int) []*AnimationPhase {
+		list := make([]*AnimationPhase, 0, n)
+		for i := 0; i < n; i++ {
+			list = append(list, &AnimationPhase{100, 100})
+		}
+		return list
+	}
+	things := []*Thing{
+		// attribute assigned twice is counted once
+		{ID: 100, Type: ITEM, Attributes: []Attribute{NewPickupable(), NewMarket(7, 100, 100, "helmet", 0, 0),
+			NewStackable(), NewPickupable()},
+			SpriteGroups: []*SpriteGroup{{Width: 1, Height: 1, Sprites: []uint32{1, 2}}}},
+		{ID: 101, Type: ITEM, Attributes: []Attribute{NewGround(150), NewPickupable(),
+			NewMarket(3, 101, 101, "boots", 0, 0)},
+			SpriteGroups: []*SpriteGroup{{Width: 2, Height: 2, AnimationPhases: phases(3),
+				Sprites: []uint32{2, 3, 0, 0}}}},
+		{ID: 1, Type: OUTFIT, SpriteGroups: []*SpriteGroup{
+			{Width: 1, Height: 1, AnimationPhases: phases(2), Sprites: []uint32{4, 0}},
+			{Width: 2, Height: 1, Sprites: []uint32{1}}}},
+		{ID: 1, Type: EFFECT, SpriteGroups: []*SpriteGroup{{Width: 1, Height: 1, Sprites: []uint32{0}}}},
+	}
+	for _, thing := range things {
+		datfh.AppendThing(thing)
+	}
+	return datfh
+}
+
+func TestStats(t *testing.T) {
+	stats := Stats(statsTestFile())
+	want := &FileStats{
+		Categories: []*CategoryStats{
+			{ITEM, 2, 2}, {OUTFIT, 1, 2}, {EFFECT, 1, 1}, {MISSILE, 0, 0},
+		},
+		// ordered by opcode, size (width first), phases and category
+		Attributes:       []*Count{{"ground", 1}, {"stackable", 1}, {"pickupable", 2}, {"market", 2}},
+		SpriteSizes:      []*Count{{"1x1", 3}, {"2x1", 1}, {"2x2", 1}},
+		AnimationPhases:  []*Count{{"1", 3}, {"2", 1}, {"3", 1}},
+		MarketCategories: []*Count{{"boots", 1}, {"helmetsHats", 1}},
+		UniqueSprites:    4,
+	}
+	if !reflect.DeepEqual(stats, want) {
+		got, _ := json.Marshal(stats)
+		wanted, _ := json.Marshal(want)
+		t.Errorf("got stats\n%s\nwant\n%s", got, wanted)
+	}
+}
+
+func TestStatsOutput(t *testing.T) {
+	stats := Stats(statsTestFile())
+
+	buf := &bytes.Buffer{}
+	if err := stats.WriteText(buf); err != nil {
+		t.Fatal(err)
+	}
+	wantText := `item: 2 things, 2 sprite groups
+outfit: 1 things, 2 sprite groups
+effect: 1 things, 1 sprite groups
+missile: 0 things, 0 sprite groups
+unique sprites: 4
+attributes:
+    ground: 1
+    stackable: 1
+    pickupable: 2
+    market: 2
+sprite sizes:
+    1x1: 3
+    2x1: 1
+    2x2: 1
+animation phases:
+    1: 3
+    2: 1
+    3: 1
+market categories:
+    boots: 1
+    helmetsHats: 1
+`
+	if buf.String() != wantText {
+		t.Errorf("got text\n%s\nwant\n%s", buf, wantText)
+	}
+
+	buf.Reset()
+	if err := stats.WriteMarkdown(buf); err != nil {
+		t.Fatal(err)
+	}
+	wantMarkdown := `## Categories
+
+| Category | Things | Sprite groups |
+| --- | ---: | ---: |
+| item | 2 | 2 |
+| outfit | 1 | 2 |
+| effect | 1 | 1 |
+| missile | 0 | 0 |
+
+Unique sprites: 4
+
+## Attributes
+
+| Value | Count |
+| --- | ---: |
+| ground | 1 |
+| stackable | 1 |
+| pickupable | 2 |
+| market | 2 |
+
+## Sprite sizes
+
+| Value | Count |
+| --- | ---: |
+| 1x1 | 3 |
+| 2x1 | 1 |
+| 2x2 | 1 |
+
+## Animation phases
+
+| Value | Count |
+| --- | ---: |
+| 1 | 3 |
+| 2 | 1 |
+| 3 | 1 |
+
+## Market categories
+
+| Value | Count |
+| --- | ---: |
+| boots | 1 |
+| helmetsHats | 1 |
+`
+	if buf.String() != wantMarkdown {
+		t.Errorf("got Markdown\n%s\nwant\n%s", buf, wantMarkdown)
+	}
+
+	buf.Reset()
+	if err := stats.WriteJSON(buf); err != nil {
+		t.Fatal(err)
+	}
+	decoded := &FileStats{}
+	if err := json.Unmarshal(buf.Bytes(), decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, stats) {
+		t.Errorf("JSON decodes into different stats:\n%s", buf)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte(`"uniqueSprites": 4`)) {
+		t.Errorf("JSON lacks uniqueSprites:\n%s", buf)
+	}
+}
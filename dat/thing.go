@@ -2,17 +2,20 @@ package dat
 
 import (
 	"encoding/xml"
+	"fmt"
 
 	bin "github.com/go-otserv/encoding/binary"
 )
 
-// Thing holds thing information: ID, type, attributes and sprites information
+// Thing holds thing information: ID, type, attributes and sprites information.
+// ID is client ID of thing within its category, the one client uses, i.e.
+// items are numbered from 100, outfits, effects and missiles from 1
 type Thing struct {
 	XMLName      xml.Name       `xml:"thing"`
 	ID           uint16         `xml:"id,attr"`
 	Type         string         `xml:"type,attr"`
-	Attributes   []Attribute    `xml:"Attribute,omitempty"`
-	SpriteGroups []*SpriteGroup `xml:"-"`
+	Attributes   []Attribute    `xml:"attr"`
+	SpriteGroups []*SpriteGroup `xml:"spriteGroup"`
 }
 
 // Some docstring to those constants
@@ -23,49 +26,146 @@ const (
 	MISSILE = "missile"
 )
 
+// typeNames lists thing types in order of appearance in .dat file
+var typeNames = []string{ITEM, OUTFIT, EFFECT, MISSILE}
+
+// firstThingID returns client ID of the first thing of given type, items are
+// numbered from 100, other things from 1
+func firstThingID(typ string) uint16 {
+	if typ == ITEM {
+		return 100
+	}
+	return 1
+}
+
 // NewThing creates new instance of Thing
 func NewThing(id uint16, typ string) *Thing {
 	return &Thing{ID: id, Type: typ}
 }
 
-// DeserializeThing parses .dat file and creates new Thing instance
+// Has reports whether thing has attribute of given opcode
+func (thing *Thing) Has(op OpCode) bool {
+	return thing.Attribute(op) != nil
+}
+
+// Attribute returns first attribute of given opcode or nil if thing doesn't
+// have one
+func (thing *Thing) Attribute(op OpCode) Attribute {
+	for _, attr := range thing.Attributes {
+		if attr.OpCode() == op {
+			return attr
+		}
+	}
+	return nil
+}
+
+// Flags returns bitset of opcodes of all thing attributes
+func (thing *Thing) Flags() Flags {
+	var flags Flags
+	for _, attr := range thing.Attributes {
+		flags.Set(attr.OpCode())
+	}
+	return flags
+}
+
+// SetAttribute replaces attribute of the same opcode as given one, or
+// appends it if thing doesn't have such attribute
+func (thing *Thing) SetAttribute(attr Attribute) {
+	for i, current := range thing.Attributes {
+		if current.OpCode() == attr.OpCode() {
+			thing.Attributes[i] = attr
+			return
+		}
+	}
+	thing.Attributes = append(thing.Attributes, attr)
+}
+
+// UnsetAttribute removes all attributes of given opcode. It reports whether
+// any attribute was removed
+func (thing *Thing) UnsetAttribute(op OpCode) bool {
+	attrs := thing.Attributes[:0]
+	for _, attr := range thing.Attributes {
+		if attr.OpCode() != op {
+			attrs = append(attrs, attr)
+		}
+	}
+	removed := len(attrs) != len(thing.Attributes)
+	thing.Attributes = attrs
+	return removed
+}
+
+// Clone returns deep copy of thing
+func (thing *Thing) Clone() *Thing {
+	clone := NewThing(thing.ID, thing.Type)
+	clone.Attributes = make([]Attribute, 0, len(thing.Attributes))
+	for _, attr := range thing.Attributes {
+		clone.Attributes = append(clone.Attributes, cloneAttribute(attr))
+	}
+	clone.SpriteGroups = make([]*SpriteGroup, 0, len(thing.SpriteGroups))
+	for _, sprGr := range thing.SpriteGroups {
+		clone.SpriteGroups = append(clone.SpriteGroups, sprGr.Clone())
+	}
+	return clone
+}
+
+// Get returns first attribute of type T assigned to thing, e.g.
+// Get[*Light](thing). Second returned value is false if there is no such
+// attribute.
+func Get[T Attribute](thing *Thing) (T, bool) {
+	for _, attr := range thing.Attributes {
+		if typed, ok := attr.(T); ok {
+			return typed, true
+		}
+	}
+	var zero T
+	return zero, false
+}
+
+// DeserializeThing parses .dat file of DefaultFeatures and creates new Thing
+// instance. Unknown attribute opcodes are kept as Unknown attributes
 func DeserializeThing(id uint16, typ string, datfh bin.Reader) (*Thing, error) {
+	return deserializeThing(id, typ, datfh, defaultCodec)
+}
+
+func deserializeThing(id uint16, typ string, datfh bin.Reader, c codec) (*Thing, error) {
 	var err error
 	thing := NewThing(id, typ)
-	if err = thing.deserializeAttributes(datfh); err != nil {
+	if err = thing.deserializeAttributes(datfh, c); err != nil {
 		return thing, err
 	}
-	if err = thing.deserializeSpritesInfo(datfh); err != nil {
+	if err = thing.deserializeSpritesInfo(datfh, c.features); err != nil {
 		return thing, err
 	}
 	return thing, nil
 }
 
-func (thing *Thing) deserializeAttributes(datfh bin.Reader) error {
+func (thing *Thing) deserializeAttributes(datfh bin.Reader, c codec) error {
 	var err error
-	var attrOp uint8
+	var rawOp uint8
 	var attr Attribute
 
 	thing.Attributes = make([]Attribute, 0, 5)
 
-	for attrOp != 255 {
-		if attrOp, err = datfh.UInt8(); err != nil {
+	for {
+		if rawOp, err = datfh.UInt8(); err != nil {
 			return err
 		}
-		if attr, err = deserializeAttribute(attrOp, datfh); err != nil {
+		if OpCode(rawOp) == OpEnd {
+			return nil
+		}
+		if attr, err = deserializeAttribute(c.decodeOpCode(rawOp), datfh, c.strict); err != nil {
 			return err
 		}
 		thing.Attributes = append(thing.Attributes, attr)
 	}
-	return nil
 }
 
-func (thing *Thing) deserializeSpritesInfo(datfh bin.Reader) error {
+func (thing *Thing) deserializeSpritesInfo(datfh bin.Reader, features Features) error {
 	var err error
 	var groupsCount uint8
 	var sprGr *SpriteGroup
 
-	if thing.Type == OUTFIT {
+	if thing.Type == OUTFIT && features.FrameGroups {
 		if groupsCount, err = datfh.UInt8(); err != nil {
 			return err
 		}
@@ -76,7 +176,7 @@ func (thing *Thing) deserializeSpritesInfo(datfh bin.Reader) error {
 	thing.SpriteGroups = make([]*SpriteGroup, 0, groupsCount)
 
 	for group := 1; group <= int(groupsCount); group++ {
-		if sprGr, err = deserializeSpriteGroup(thing.Type, datfh); err != nil {
+		if sprGr, err = deserializeSpriteGroup(thing.Type, datfh, features); err != nil {
 			return err
 		}
 		sprGr.Group = group
@@ -84,3 +184,48 @@ func (thing *Thing) deserializeSpritesInfo(datfh bin.Reader) error {
 	}
 	return nil
 }
+
+// Serialize writes thing attributes and sprites information in .dat format of
+// DefaultFeatures
+func (thing *Thing) Serialize(datfh bin.Writer) error {
+	return thing.serialize(datfh, defaultCodec)
+}
+
+func (thing *Thing) serialize(datfh bin.Writer, c codec) error {
+	features := c.features
+	for _, attr := range thing.Attributes {
+		rawOp, ok := c.encodeOpCode(attr.OpCode())
+		if !ok {
+			return fmt.Errorf("%s %d: attribute %s is not supported by client %d",
+				thing.Type, thing.ID, attr.OpCode(), c.profile.Client)
+		}
+		if err := datfh.PutUInt8(rawOp); err != nil {
+			return err
+		}
+		if err := serializeAttributeData(attr, datfh); err != nil {
+			return err
+		}
+	}
+	if err := datfh.PutUInt8(uint8(OpEnd)); err != nil {
+		return err
+	}
+
+	if thing.Type == OUTFIT && features.FrameGroups {
+		if len(thing.SpriteGroups) > 255 {
+			return fmt.Errorf("Too many sprite groups: %d", len(thing.SpriteGroups))
+		}
+		if err := datfh.PutUInt8(uint8(len(thing.SpriteGroups))); err != nil {
+			return err
+		}
+	} else if len(thing.SpriteGroups) != 1 {
+		return fmt.Errorf("Thing %d of type %s must have exactly one sprite group, has %d",
+			thing.ID, thing.Type, len(thing.SpriteGroups))
+	}
+
+	for _, sprGr := range thing.SpriteGroups {
+		if err := sprGr.serialize(thing.Type, datfh, features); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package dat
+
+import "sort"
+
+// SpriteIndex provides addresses of sprites data, e.g. spr.File. Address 0
+// means sprite is empty
+type SpriteIndex interface {
+	SpriteAddress(id int) (uint32, error)
+}
+
+// ThingRef identifies thing by its type and client ID
+type ThingRef struct {
+	Type string `json:"type"`
+	ID   uint16 `json:"id"`
+}
+
+// SharedSprite is sprite referenced by more than one thing
+type SharedSprite struct {
+	Sprite uint32     `json:"sprite"`
+	Things []ThingRef `json:"things"`
+}
+
+// SpriteUsage holds result of AnalyzeSprites. Unused lists sprites no thing
+// references, Shared sprites referenced by multiple things, Empty things
+// whose sprites are all empty and Missing sprite IDs referenced by things,
+// but missing in .spr file. All lists are sorted
+type SpriteUsage struct {
+	Unused  []uint32        `json:"unused"`
+	Shared  []*SharedSprite `json:"shared"`
+	Empty   []ThingRef      `json:"empty"`
+	Missing []uint32        `json:"missing"`
+}
+
+// AnalyzeSprites cross-references sprites of all things of file with .spr
+// file of given number of sprites
+func AnalyzeSprites(datfh *File, sprites SpriteIndex, spritesCount uint32) (*SpriteUsage, error) {
+	usage := &SpriteUsage{
+		Unused:  make([]uint32, 0),
+		Shared:  make([]*SharedSprite, 0),
+		Empty:   make([]ThingRef, 0),
+		Missing: make([]uint32, 0),
+	}
+	users := make(map[uint32][]ThingRef)
+	empty := make(map[uint32]bool)
+
+	for _, typ := range typeNames {
+		for _, thing := range datfh.Category(typ) {
+			ref := ThingRef{thing.Type, thing.ID}
+			allEmpty := true
+			for _, sprGr := range thing.SpriteGroups {
+				for _, sprID := range sprGr.Sprites {
+					if sprID == 0 {
+						continue
+					}
+					if sprID > spritesCount {
+						allEmpty = false
+						if _, ok := users[sprID]; !ok {
+							usage.Missing = append(usage.Missing, sprID)
+						}
+					} else if _, ok := empty[sprID]; !ok {
+						address, err := sprites.SpriteAddress(int(sprID))
+						if err != nil {
+							return nil, err
+						}
+						empty[sprID] = address == 0
+					}
+					if !empty[sprID] {
+						allEmpty = false
+					}
+					refs := users[sprID]
+					if len(refs) == 0 || refs[len(refs)-1] != ref {
+						users[sprID] = append(refs, ref)
+					}
+				}
+			}
+			if allEmpty {
+				usage.Empty = append(usage.Empty, ref)
+			}
+		}
+	}
+
+	for sprID := uint32(1); sprID <= spritesCount; sprID++ {
+		if _, ok := users[sprID]; !ok {
+			usage.Unused = append(usage.Unused, sprID)
+		}
+	}
+	for sprID, refs := range users {
+		if len(refs) > 1 {
+			usage.Shared = append(usage.Shared, &SharedSprite{sprID, refs})
+		}
+	}
+	sort.Slice(usage.Shared, func(i, j int) bool {
+		return usage.Shared[i].Sprite < usage.Shared[j].Sprite
+	})
+	sort.Slice(usage.Missing, func(i, j int) bool {
+		return usage.Missing[i] < usage.Missing[j]
+	})
+	return usage, nil
+}
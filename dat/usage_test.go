@@ -0,0 +1,77 @@
+package dat
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// spriteAddresses is SpriteIndex of sprites of given addresses, sprite at
+// index i has ID i+1
+type spriteAddresses []uint32
+
+func (addresses spriteAddresses) SpriteAddress(id int) (uint32, error) {
+	if id == 0 {
+		return 0, nil
+	}
+	if id < 0 || id > len(addresses) {
+		return 0, fmt.Errorf("Sprite %d out of range", id)
+	}
+	return addresses[id-1], nil
+}
+
+func TestAnalyzeSprites(t *testing.T) {
+	// sprites 3 and 6 are empty
+	sprites := spriteAddresses{100, 200, 0, 300, 400, 0, 500}
+	things := []struct {
+		typ     string
+		id      uint16
+		sprites []uint32
+	}{
+		// the same sprite listed several times counts as one user
+		{ITEM, 100, []uint32{1, 1, 2, 1}},
+		{ITEM, 101, []uint32{2, 9}},
+		{ITEM, 102, []uint32{3, 0, 6}},
+		{ITEM, 103, []uint32{0}},
+		{OUTFIT, 1, []uint32{4, 2, 8, 9}},
+		{EFFECT, 1, []uint32{3}},
+	}
+	datfh := NewFile(0)
+	for _, th := range things {
+		thing := NewThing(th.id, th.typ)
+		thing.SpriteGroups = []*SpriteGroup{{Sprites: th.sprites}}
+		datfh.AppendThing(thing)
+	}
+
+	usage, err := AnalyzeSprites(datfh, sprites, uint32(len(sprites)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &SpriteUsage{
+		Unused: []uint32{5, 7},
+		Shared: []*SharedSprite{
+			{2, []ThingRef{{ITEM, 100}, {ITEM, 101}, {OUTFIT, 1}}},
+			{3, []ThingRef{{ITEM, 102}, {EFFECT, 1}}},
+			{9, []ThingRef{{ITEM, 101}, {OUTFIT, 1}}},
+		},
+		Empty:   []ThingRef{{ITEM, 102}, {ITEM, 103}, {EFFECT, 1}},
+		Missing: []uint32{8, 9},
+	}
+	if !reflect.DeepEqual(usage, want) {
+		t.Errorf("got usage %+v, want %+v", usage, want)
+		for _, shared := range usage.Shared {
+			t.Logf("shared %d: %v", shared.Sprite, shared.Things)
+		}
+	}
+}
+
+func TestAnalyzeSpritesError(t *testing.T) {
+	datfh := NewFile(0)
+	thing := NewThing(100, ITEM)
+	thing.SpriteGroups = []*SpriteGroup{{Sprites: []uint32{2}}}
+	datfh.AppendThing(thing)
+	// index reports fewer sprites than file header
+	if _, err := AnalyzeSprites(datfh, spriteAddresses{100}, 2); err == nil {
+		t.Error("AnalyzeSprites succeeded with failing sprite index")
+	}
+}
@@ -0,0 +1,232 @@
+package dat
+
+import (
+	"fmt"
+
+	"github.com/go-otserv/encoding/spr"
+)
+
+// Severity of validation finding
+type Severity int
+
+// Severities of validation findings
+const (
+	SeverityWarning Severity = iota
+	SeverityError
+)
+
+// String implements fmt.Stringer interface
+func (severity Severity) String() string {
+	switch severity {
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	}
+	return fmt.Sprintf("Severity<%d>", int(severity))
+}
+
+// Codes of validation findings
+const (
+	FindingBadID             = "bad-id"
+	FindingSpriteOutOfRange  = "sprite-out-of-range"
+	FindingBadDimensions     = "bad-dimensions"
+	FindingBadSpritesCount   = "bad-sprites-count"
+	FindingBadAnimation      = "bad-animation"
+	FindingBadFrameGroups    = "bad-frame-groups"
+	FindingExclusiveFlags    = "exclusive-flags"
+	FindingDuplicatedAttr    = "duplicated-attribute"
+	FindingUnknownAttr       = "unknown-attribute"
+	FindingMissingFrameGroup = "missing-frame-group"
+)
+
+// exclusiveAttributes lists pairs of attributes which can't be assigned to
+// the same thing
+var exclusiveAttributes = [][2]OpCode{
+	{OpOnBottom, OpOnTop},
+	{OpGround, OpOnBottom},
+	{OpGround, OpOnTop},
+	{OpWritable, OpWritableOnce},
+	{OpStackable, OpContainer},
+	{OpStackable, OpFluidContainer},
+	{OpStackable, OpSplash},
+	{OpContainer, OpFluidContainer},
+}
+
+// Finding describes single problem found by Validate. Group is 1-based
+// position of sprite group or 0 if finding concerns the thing itself
+type Finding struct {
+	Severity Severity `json:"severity"`
+	Code     string   `json:"code"`
+	Type     string   `json:"type"`
+	ID       uint16   `json:"id"`
+	Group    int      `json:"group,omitempty"`
+	Message  string   `json:"message"`
+}
+
+// String implements fmt.Stringer interface
+func (finding *Finding) String() string {
+	where := fmt.Sprintf("%s %d", finding.Type, finding.ID)
+	if finding.Group > 0 {
+		where += fmt.Sprintf(" sprite group %d", finding.Group)
+	}
+	return fmt.Sprintf("%s: %s: %s [%s]", finding.Severity, where, finding.Message,
+		finding.Code)
+}
+
+// Validate checks consistency of things of given file. Sprite IDs are checked
+// against sprites count of given .spr file, unless it's nil. Frame groups of
+// outfits are checked only if features of file include them
+func Validate(datfh *File, sprfh *spr.File) []*Finding {
+	v := &validator{
+		findings:    make([]*Finding, 0),
+		frameGroups: datfh.codec().features.FrameGroups,
+	}
+	if sprfh != nil {
+		v.spritesCount = sprfh.SpritesCount
+		v.checkSprites = true
+	}
+	for _, typ := range typeNames {
+		expectedID := nextThingID(typ, nil)
+		for _, thing := range datfh.Category(typ) {
+			if thing.ID != expectedID || thing.Type != typ {
+				v.add(thing, 0, SeverityError, FindingBadID, fmt.Sprintf(
+					"expected %s %d at this position", typ, expectedID))
+			}
+			expectedID++
+			v.validateThing(thing)
+		}
+	}
+	return v.findings
+}
+
+type validator struct {
+	findings     []*Finding
+	spritesCount uint32
+	checkSprites bool
+	frameGroups  bool
+}
+
+func (v *validator) add(thing *Thing, group int, severity Severity, code, message string) {
+	v.findings = append(v.findings, &Finding{severity, code, thing.Type, thing.ID, group, message})
+}
+
+func (v *validator) validateThing(thing *Thing) {
+	flags := thing.Flags()
+	seen := Flags{}
+	for _, attr := range thing.Attributes {
+		op := attr.OpCode()
+		if seen.Has(op) {
+			v.add(thing, 0, SeverityWarning, FindingDuplicatedAttr,
+				fmt.Sprintf("attribute %s is assigned more than once", op))
+		}
+		seen.Set(op)
+		if _, ok := attr.(*Unknown); ok {
+			v.add(thing, 0, SeverityWarning, FindingUnknownAttr,
+				fmt.Sprintf("unknown attribute opcode %d", uint8(op)))
+		}
+	}
+	for _, pair := range exclusiveAttributes {
+		if flags.Has(pair[0]) && flags.Has(pair[1]) {
+			v.add(thing, 0, SeverityError, FindingExclusiveFlags,
+				fmt.Sprintf("attributes %s and %s are mutually exclusive", pair[0], pair[1]))
+		}
+	}
+
+	if thing.Type == OUTFIT && v.frameGroups {
+		v.validateFrameGroups(thing)
+	} else if len(thing.SpriteGroups) != 1 {
+		v.add(thing, 0, SeverityError, FindingBadFrameGroups, fmt.Sprintf(
+			"expected exactly one sprite group, found %d", len(thing.SpriteGroups)))
+	}
+
+	for i, sprGr := range thing.SpriteGroups {
+		v.validateSpriteGroup(thing, i+1, sprGr)
+	}
+}
+
+func (v *validator) validateFrameGroups(thing *Thing) {
+	var types [256]int
+	for i, sprGr := range thing.SpriteGroups {
+		switch sprGr.FrameGroupType {
+		case FrameGroupIdle, FrameGroupMoving:
+		default:
+			v.add(thing, i+1, SeverityError, FindingBadFrameGroups,
+				fmt.Sprintf("unknown frame group type %d", sprGr.FrameGroupType))
+		}
+		types[sprGr.FrameGroupType]++
+	}
+	for typ, count := range types {
+		if count > 1 {
+			v.add(thing, 0, SeverityError, FindingBadFrameGroups,
+				fmt.Sprintf("frame group type %d is defined %d times", typ, count))
+		}
+	}
+
+	// outfits of single idle group, e.g. of creatures which don't move, are
+	// valid, outfits of several groups need both idle and walk group
+	if len(thing.SpriteGroups) == 1 {
+		if types[FrameGroupIdle] == 0 {
+			v.add(thing, 0, SeverityWarning, FindingMissingFrameGroup,
+				"outfit has no idle frame group")
+		}
+		return
+	}
+	if types[FrameGroupIdle] == 0 {
+		v.add(thing, 0, SeverityError, FindingMissingFrameGroup, "outfit has no idle frame group")
+	}
+	if types[FrameGroupMoving] == 0 {
+		v.add(thing, 0, SeverityError, FindingMissingFrameGroup, "outfit has no walk frame group")
+	}
+}
+
+func (v *validator) validateSpriteGroup(thing *Thing, group int, sprGr *SpriteGroup) {
+	dimensions := []struct {
+		name string
+		val  uint8
+	}{
+		{"width", sprGr.Width},
+		{"height", sprGr.Height},
+		{"layers", sprGr.Layers},
+		{"patternX", sprGr.PatternXNum},
+		{"patternY", sprGr.PatternYNum},
+		{"patternZ", sprGr.PatternZNum},
+	}
+	for _, dim := range dimensions {
+		if dim.val == 0 {
+			v.add(thing, group, SeverityError, FindingBadDimensions,
+				fmt.Sprintf("%s is 0", dim.name))
+		}
+	}
+
+	phases := sprGr.phasesCount()
+	if phases > 1 && int(sprGr.StartPhase) >= phases {
+		v.add(thing, group, SeverityError, FindingBadAnimation, fmt.Sprintf(
+			"start phase %d exceeds %d phases", sprGr.StartPhase, phases))
+	}
+	for i, animPhase := range sprGr.AnimationPhases {
+		if animPhase.FrameA > animPhase.FrameB {
+			v.add(thing, group, SeverityWarning, FindingBadAnimation, fmt.Sprintf(
+				"phase %d minimum duration %d exceeds maximum %d", i,
+				animPhase.FrameA, animPhase.FrameB))
+		}
+	}
+
+	expected := int(sprGr.Width) * int(sprGr.Height) * int(sprGr.Layers) *
+		int(sprGr.PatternXNum) * int(sprGr.PatternYNum) * int(sprGr.PatternZNum) *
+		phases
+	if expected != len(sprGr.Sprites) {
+		v.add(thing, group, SeverityError, FindingBadSpritesCount, fmt.Sprintf(
+			"expected %d sprites, found %d", expected, len(sprGr.Sprites)))
+	}
+
+	if !v.checkSprites {
+		return
+	}
+	for i, sprID := range sprGr.Sprites {
+		if sprID > v.spritesCount {
+			v.add(thing, group, SeverityError, FindingSpriteOutOfRange, fmt.Sprintf(
+				"sprite %d at index %d exceeds sprites count %d", sprID, i, v.spritesCount))
+		}
+	}
+}
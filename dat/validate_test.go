@@ -0,0 +1,48 @@
+package dat
+
+import "testing"
+
+// frameGroupFindings returns findings of frame groups of outfits of file
+func frameGroupFindings(datfh *File) []*Finding {
+	findings := make([]*Finding, 0)
+	for _, finding := range Validate(datfh, nil) {
+		if finding.Code == FindingBadFrameGroups || finding.Code == FindingMissingFrameGroup {
+			findings = append(findings, finding)
+		}
+	}
+	return findings
+}
+
+func TestValidateFrameGroups(t *testing.T) {
+	single := newTestSpriteGroup(1, 1, 2, 4, 1, 1, 1, 1)
+	idle := newTestSpriteGroup(1, 1, 2, 4, 1, 1, 1, 1)
+	walk := newTestSpriteGroup(1, 1, 2, 4, 1, 1, 2, 1)
+	walk.FrameGroupType = FrameGroupMoving
+	walk2 := walk.Clone()
+
+	tests := []struct {
+		name        string
+		frameGroups bool
+		groups      []*SpriteGroup
+		want        int
+	}{
+		{"single group", true, []*SpriteGroup{single}, 0},
+		{"single walk group", true, []*SpriteGroup{walk}, 1},
+		{"no groups", true, []*SpriteGroup{}, 2},
+		{"idle and walk", true, []*SpriteGroup{idle, walk}, 0},
+		{"two walk groups", true, []*SpriteGroup{walk, walk2}, 2},
+		{"walk and idle", true, []*SpriteGroup{walk, idle}, 0},
+		{"no frame groups", false, []*SpriteGroup{single}, 0},
+		{"no frame groups, two groups", false, []*SpriteGroup{idle, walk}, 1},
+	}
+	for _, test := range tests {
+		datfh := NewFile(0)
+		datfh.Features = &Features{Extended: true, FrameGroups: test.frameGroups}
+		outfit := NewThing(1, OUTFIT)
+		outfit.SpriteGroups = test.groups
+		datfh.AppendThing(outfit)
+		if findings := frameGroupFindings(datfh); len(findings) != test.want {
+			t.Errorf("%s: got findings %v, want %d", test.name, findings, test.want)
+		}
+	}
+}
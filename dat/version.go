@@ -0,0 +1,208 @@
+package dat
+
+import "fmt"
+
+// Codes of findings reported by Convert
+const (
+	FindingDroppedAttribute  = "dropped-attribute"
+	FindingDroppedFrameGroup = "dropped-frame-group"
+	FindingDroppedAnimation  = "dropped-animation"
+	FindingSpriteIDTooBig    = "sprite-id-too-big"
+)
+
+// minClientVersion is the oldest client version of known .dat format
+const minClientVersion = 755
+
+// Profile describes .dat format of single client version: optional parts of
+// format and attribute opcodes. Attributes of File are always identified by
+// opcodes of recent clients, Profile maps them to opcodes of its version
+type Profile struct {
+	Client   int
+	Features Features
+}
+
+// NewProfile returns profile of given client version, e.g. 860 for 8.60
+func NewProfile(client int) (*Profile, error) {
+	if client < minClientVersion {
+		return nil, fmt.Errorf("Unsupported client version: %d", client)
+	}
+	return &Profile{
+		Client: client,
+		Features: Features{
+			Extended:           client >= 960,
+			FrameGroups:        client >= 1057,
+			ImprovedAnimations: client >= 1050,
+		},
+	}, nil
+}
+
+// Supports reports whether attribute of given opcode exists in client
+// version. Opcodes of custom and unknown attributes are always supported
+func (profile *Profile) Supports(op OpCode) bool {
+	switch op {
+	case OpNoMoveAnimation, OpWrapable, OpUnwrapable, OpTopEffect:
+		return profile.Client >= 1000
+	case OpMarket:
+		return profile.Client >= 940
+	case OpTranslucent:
+		return profile.Client >= 780
+	case OpFloorChange:
+		return profile.Client < 780
+	case OpDeprecated:
+		return profile.Client >= 780 && profile.Client < 860
+	}
+	return true
+}
+
+// decodeOpCode converts opcode of client version into opcode of recent
+// clients. Clients before 10.00 have no opcode 16, clients 7.80 - 8.54 use
+// opcode 8 for charges and clients 7.55 - 7.72 use opcode 23 for floor change
+func (profile *Profile) decodeOpCode(raw uint8) OpCode {
+	if raw >= uint8(OpOpacity) || profile.Client >= 1000 {
+		return OpCode(raw)
+	}
+	switch {
+	case profile.Client < 780:
+		if raw == 23 {
+			return OpFloorChange
+		}
+	case profile.Client < 860:
+		if raw == 8 {
+			return OpDeprecated
+		}
+		if raw > 8 {
+			raw--
+		}
+	}
+	if raw >= uint8(OpNoMoveAnimation) {
+		raw++
+	}
+	return OpCode(raw)
+}
+
+// encodeOpCode is inverse of decodeOpCode, second returned value is false if
+// attribute isn't supported by client version
+func (profile *Profile) encodeOpCode(op OpCode) (uint8, bool) {
+	if !profile.Supports(op) {
+		return 0, false
+	}
+	switch {
+	case op == OpFloorChange && profile.Client < 780:
+		return 23, true
+	case op == OpDeprecated:
+		return 8, true
+	case op >= OpOpacity || profile.Client >= 1000:
+		return uint8(op), true
+	}
+	raw := uint8(op)
+	if raw > uint8(OpNoMoveAnimation) {
+		raw--
+	}
+	if profile.Client >= 780 && profile.Client < 860 && raw >= 8 {
+		raw++
+	}
+	return raw, true
+}
+
+// Convert returns copy of file in format of given profile. Attributes not
+// supported by target client are dropped, without frame groups outfits keep
+// only walk frame group, and frame groups are emulated when converting to
+// client which has them. Without improved animations animation settings are
+// reset to defaults. Every lossy change is reported as warning, sprite
+// IDs which can't be written without extended format are reported as errors
+func Convert(datfh *File, to *Profile) (*File, []*Finding) {
+	conv := &validator{findings: make([]*Finding, 0)}
+	converted := NewFile(datfh.Signature)
+	converted.ContentRevision = datfh.ContentRevision
+	features := to.Features
+	converted.Features = &features
+	converted.Profile = to
+
+	for _, typ := range typeNames {
+		for _, thing := range datfh.Category(typ) {
+			clone := thing.Clone()
+			conv.convertAttributes(clone, to)
+			conv.convertSpriteGroups(clone, to.Features)
+			converted.AppendThing(clone)
+		}
+	}
+	converted.resetCounts()
+	return converted, conv.findings
+}
+
+func (v *validator) convertAttributes(thing *Thing, to *Profile) {
+	kept := make([]Attribute, 0, len(thing.Attributes))
+	for _, attr := range thing.Attributes {
+		if to.Supports(attr.OpCode()) {
+			kept = append(kept, attr)
+			continue
+		}
+		v.add(thing, 0, SeverityWarning, FindingDroppedAttribute, fmt.Sprintf(
+			"attribute %s is not supported by client %d", attr.OpCode(), to.Client))
+	}
+	thing.Attributes = kept
+}
+
+func (v *validator) convertSpriteGroups(thing *Thing, features Features) {
+	if thing.Type == OUTFIT {
+		switch {
+		case !features.FrameGroups && len(thing.SpriteGroups) > 1:
+			keep := thing.FrameGroup(FrameGroupMoving)
+			if keep < 0 {
+				keep = 0
+			}
+			for i, sprGr := range thing.SpriteGroups {
+				if i != keep {
+					v.add(thing, i+1, SeverityWarning, FindingDroppedFrameGroup, fmt.Sprintf(
+						"frame group %d dropped, client has no frame groups", sprGr.FrameGroupType))
+				}
+			}
+			sprGr := thing.SpriteGroups[keep]
+			sprGr.FrameGroupType, sprGr.Group = 0, 1
+			thing.SpriteGroups = []*SpriteGroup{sprGr}
+		case features.FrameGroups && len(thing.SpriteGroups) == 1:
+			// the first phase of walk animation is used as idle outfit
+			moving := thing.SpriteGroups[0]
+			moving.FrameGroupType, moving.Group = FrameGroupMoving, 2
+			thing.SpriteGroups = []*SpriteGroup{firstPhase(moving), moving}
+		}
+	}
+
+	for i, sprGr := range thing.SpriteGroups {
+		if !features.ImprovedAnimations && len(sprGr.AnimationPhases) > 1 {
+			before := sprGr.Clone()
+			sprGr.Async, sprGr.StartPhase, sprGr.LoopCount = 0, 0, 0
+			for _, animPhase := range sprGr.AnimationPhases {
+				animPhase.FrameA, animPhase.FrameB = 0, 0
+			}
+			defaultPhaseDurations(thing.Type, sprGr)
+			if diffSpriteGroups(before, sprGr) != nil {
+				v.add(thing, i+1, SeverityWarning, FindingDroppedAnimation,
+					"async mode, start phase, loop count and phase durations replaced by defaults")
+			}
+		}
+		if features.Extended {
+			continue
+		}
+		for idx, sprID := range sprGr.Sprites {
+			if sprID > 0xFFFF {
+				v.add(thing, i+1, SeverityError, FindingSpriteIDTooBig, fmt.Sprintf(
+					"sprite %d at index %d requires extended format", sprID, idx))
+			}
+		}
+	}
+}
+
+// firstPhase returns idle frame group made of the first phase of sprite group
+func firstPhase(sprGr *SpriteGroup) *SpriteGroup {
+	idle := sprGr.Clone()
+	idle.FrameGroupType, idle.Group = FrameGroupIdle, 1
+	idle.Async, idle.StartPhase, idle.LoopCount = 0, 0, 0
+	idle.AnimationPhases = make([]*AnimationPhase, 0)
+	perPhase := int(sprGr.Width) * int(sprGr.Height) * int(sprGr.Layers) *
+		int(sprGr.PatternXNum) * int(sprGr.PatternYNum) * int(sprGr.PatternZNum)
+	if perPhase < len(idle.Sprites) {
+		idle.Sprites = idle.Sprites[:perPhase]
+	}
+	return idle
+}
@@ -0,0 +1,168 @@
+package dat
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestProfileOpCodes(t *testing.T) {
+	tests := []struct {
+		client      int
+		raw         map[OpCode]uint8
+		unsupported []OpCode
+	}{
+		{
+			client: 760,
+			raw: map[OpCode]uint8{OpWritable: 8, OpNotPathable: 15, OpPickupable: 16, OpLight: 21,
+				OpFloorChange: 23, OpOpacity: 100},
+			unsupported: []OpCode{OpNoMoveAnimation, OpTranslucent, OpMarket, OpTopEffect, OpDeprecated},
+		},
+		{
+			client: 800,
+			raw: map[OpCode]uint8{OpContainer: 4, OpDeprecated: 8, OpWritable: 9, OpPickupable: 17,
+				OpLight: 22, OpTranslucent: 24, OpOpacity: 100},
+			unsupported: []OpCode{OpNoMoveAnimation, OpFloorChange, OpMarket, OpWrapable},
+		},
+		{
+			client: 860,
+			raw: map[OpCode]uint8{OpWritable: 8, OpPickupable: 16, OpLight: 21, OpTranslucent: 23,
+				OpCloth: 32, OpOpacity: 100},
+			unsupported: []OpCode{OpNoMoveAnimation, OpFloorChange, OpDeprecated, OpMarket},
+		},
+		{
+			client: 986,
+			raw:    map[OpCode]uint8{OpPickupable: 16, OpMarket: 33, OpUsable: 34},
+			unsupported: []OpCode{OpNoMoveAnimation, OpWrapable, OpUnwrapable, OpTopEffect,
+				OpFloorChange, OpDeprecated},
+		},
+		{
+			client:      1098,
+			raw:         map[OpCode]uint8{OpNoMoveAnimation: 16, OpPickupable: 17, OpTopEffect: 38, OpOpacity: 100},
+			unsupported: []OpCode{OpFloorChange, OpDeprecated},
+		},
+	}
+	for _, tt := range tests {
+		profile, err := NewProfile(tt.client)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c := codec{profile: profile}
+
+		for op, want := range tt.raw {
+			if raw, ok := c.encodeOpCode(op); !ok || raw != want {
+				t.Errorf("client %d: expected %s encoded as %d, got %d (%t)", tt.client, op, want, raw, ok)
+			}
+		}
+		for _, op := range tt.unsupported {
+			if profile.Supports(op) {
+				t.Errorf("client %d: expected %s to be unsupported", tt.client, op)
+			}
+			if _, ok := c.encodeOpCode(op); ok {
+				t.Errorf("client %d: expected %s not to be encoded", tt.client, op)
+			}
+		}
+
+		// every supported opcode is decoded back and no two share raw opcode
+		encoded := make(map[uint8]OpCode)
+		for op := range opNames {
+			if op == OpEnd || !profile.Supports(op) {
+				continue
+			}
+			raw, ok := c.encodeOpCode(op)
+			if !ok {
+				t.Errorf("client %d: supported %s not encoded", tt.client, op)
+				continue
+			}
+			if other, dup := encoded[raw]; dup {
+				t.Errorf("client %d: %s and %s both encoded as %d", tt.client, op, other, raw)
+			}
+			encoded[raw] = op
+			if got := c.decodeOpCode(raw); got != op {
+				t.Errorf("client %d: %s encoded as %d decoded as %s", tt.client, op, raw, got)
+			}
+		}
+	}
+
+	if _, err := NewProfile(740); err == nil {
+		t.Error("expected error creating profile of client 7.40")
+	}
+}
+
+func TestConvert(t *testing.T) {
+	datfh := newTestFile(3)
+	to, err := NewProfile(860)
+	if err != nil {
+		t.Fatal(err)
+	}
+	converted, findings := Convert(datfh, to)
+
+	type finding struct {
+		code  string
+		typ   string
+		id    uint16
+		group int
+	}
+	want := []finding{
+		{FindingDroppedAttribute, ITEM, 101, 0},
+		{FindingDroppedAttribute, ITEM, 101, 0},
+		{FindingDroppedAnimation, ITEM, 102, 1},
+		{FindingDroppedFrameGroup, OUTFIT, 1, 1},
+		{FindingDroppedAnimation, OUTFIT, 1, 1},
+		{FindingDroppedAttribute, EFFECT, 1, 0},
+		{FindingDroppedAnimation, EFFECT, 1, 1},
+	}
+	for i := 0; i < 9; i++ {
+		want = append(want, finding{FindingSpriteIDTooBig, MISSILE, 1, 1})
+	}
+	got := make([]finding, 0, len(findings))
+	for _, f := range findings {
+		got = append(got, finding{f.Code, f.Type, f.ID, f.Group})
+		wantSeverity := SeverityWarning
+		if f.Code == FindingSpriteIDTooBig {
+			wantSeverity = SeverityError
+		}
+		if f.Severity != wantSeverity {
+			t.Errorf("expected severity %v of %v", wantSeverity, f)
+		}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected findings %v, got %v", want, got)
+	}
+
+	if converted.Profile != to || *converted.Features != to.Features {
+		t.Errorf("expected converted file of client %d", to.Client)
+	}
+	if item := converted.Items[1]; item.Has(OpMarket) || item.Has(OpDeprecated) || !item.Has(OpLight) {
+		t.Errorf("expected market and deprecated attributes dropped, got %v", item.Attributes)
+	}
+	if !datfh.Items[1].Has(OpMarket) {
+		t.Error("expected source file to be unchanged")
+	}
+	outfit := converted.Outfits[0]
+	if len(outfit.SpriteGroups) != 1 || outfit.SpriteGroups[0].Group != 1 ||
+		outfit.SpriteGroups[0].FrameGroupType != 0 || outfit.SpriteGroups[0].Sprites[0] != 100 {
+		t.Errorf("expected walk frame group kept, got %+v", outfit.SpriteGroups)
+	}
+	legacy := uint32(EffectFrameDuration / time.Millisecond)
+	for _, animPhase := range converted.Effects[0].SpriteGroups[0].AnimationPhases {
+		if animPhase.FrameA != legacy || animPhase.FrameB != legacy {
+			t.Errorf("expected legacy phase duration, got %+v", animPhase)
+		}
+	}
+
+	// single frame group is split into idle and walk groups
+	back, err := NewProfile(1098)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored, findings := Convert(converted, back)
+	if len(findings) != 0 {
+		t.Errorf("expected no findings, got %v", findings)
+	}
+	sprGrs := restored.Outfits[0].SpriteGroups
+	if len(sprGrs) != 2 || sprGrs[0].FrameGroupType != FrameGroupIdle || sprGrs[1].FrameGroupType != FrameGroupMoving ||
+		len(sprGrs[0].Sprites) != 48 || len(sprGrs[1].Sprites) != 96 {
+		t.Errorf("expected idle and walk frame groups, got %+v", sprGrs)
+	}
+}
@@ -0,0 +1,235 @@
+package dat
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// xmlFile is XML representation of whole File
+type xmlFile struct {
+	XMLName         xml.Name `xml:"dat"`
+	Signature       uint32   `xml:"signature,attr"`
+	ContentRevision uint16   `xml:"contentRevision,attr"`
+	Items           []*Thing `xml:"items>thing"`
+	Outfits         []*Thing `xml:"outfits>thing"`
+	Effects         []*Thing `xml:"effects>thing"`
+	Missiles        []*Thing `xml:"missiles>thing"`
+}
+
+// MarshalXML implements xml.Marshaler interface. Whole File: header and
+// things of every category with attributes and sprite groups is encoded
+func (datfh *File) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	start.Name = xml.Name{Local: "dat"}
+	doc := &xmlFile{
+		Signature:       datfh.Signature,
+		ContentRevision: datfh.ContentRevision,
+		Items:           datfh.Items,
+		Outfits:         datfh.Outfits,
+		Effects:         datfh.Effects,
+		Missiles:        datfh.Missiles,
+	}
+	if !datfh.ExportRGB {
+		return e.EncodeElement(doc, start)
+	}
+	// categories shadow those of embedded xmlFile
+	return e.EncodeElement(&struct {
+		*xmlFile
+		Items    []rgbThing `xml:"items>thing"`
+		Outfits  []rgbThing `xml:"outfits>thing"`
+		Effects  []rgbThing `xml:"effects>thing"`
+		Missiles []rgbThing `xml:"missiles>thing"`
+	}{doc, rgbThings(datfh.Items), rgbThings(datfh.Outfits),
+		rgbThings(datfh.Effects), rgbThings(datfh.Missiles)}, start)
+}
+
+// UnmarshalXML implements xml.Unmarshaler interface. Things already present
+// in File are replaced by decoded ones
+func (datfh *File) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	doc := &xmlFile{}
+	if err := d.DecodeElement(doc, &start); err != nil {
+		return err
+	}
+
+	datfh.Signature = doc.Signature
+	datfh.ContentRevision = doc.ContentRevision
+	datfh.Items = make([]*Thing, 0, len(doc.Items))
+	datfh.Outfits = make([]*Thing, 0, len(doc.Outfits))
+	datfh.Effects = make([]*Thing, 0, len(doc.Effects))
+	datfh.Missiles = make([]*Thing, 0, len(doc.Missiles))
+	categories := [][]*Thing{doc.Items, doc.Outfits, doc.Effects, doc.Missiles}
+	for typ, things := range categories {
+		firstID := firstThingID(typeNames[typ])
+		for i, thing := range things {
+			if thing.Type != typeNames[typ] {
+				return fmt.Errorf("Thing %d of type %q listed as %s",
+					thing.ID, thing.Type, typeNames[typ])
+			}
+			if expectedID := firstID + uint16(i); thing.ID != expectedID {
+				return fmt.Errorf("Expected %s %d at index %d, got %d",
+					thing.Type, expectedID, i, thing.ID)
+			}
+			datfh.AppendThing(thing)
+		}
+	}
+	datfh.resetCounts()
+	return nil
+}
+
+// MarshalXML implements xml.Marshaler interface
+func (thing *Thing) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return thing.marshalXML(e, start, false)
+}
+
+func (thing *Thing) marshalXML(e *xml.Encoder, start xml.StartElement, rgb bool) error {
+	start.Name = xml.Name{Local: "thing"}
+	start.Attr = []xml.Attr{
+		{Name: xml.Name{Local: "id"}, Value: strconv.Itoa(int(thing.ID))},
+		{Name: xml.Name{Local: "type"}, Value: thing.Type},
+	}
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	for _, attr := range thing.Attributes {
+		if err := marshalAttributeXML(e, attr, rgb); err != nil {
+			return err
+		}
+	}
+	for _, sprGr := range thing.SpriteGroups {
+		err := e.EncodeElement(sprGr, xml.StartElement{Name: xml.Name{Local: "spriteGroup"}})
+		if err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+// UnmarshalXML implements xml.Unmarshaler interface. Concrete attribute types
+// are restored from "name" of every attr element
+func (thing *Thing) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	for _, attr := range start.Attr {
+		switch attr.Name.Local {
+		case "id":
+			id, err := strconv.ParseUint(attr.Value, 10, 16)
+			if err != nil {
+				return err
+			}
+			thing.ID = uint16(id)
+		case "type":
+			thing.Type = attr.Value
+		}
+	}
+	thing.Attributes = make([]Attribute, 0, 5)
+	thing.SpriteGroups = make([]*SpriteGroup, 0, 1)
+
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			switch tok.Name.Local {
+			case "attr":
+				attr, err := unmarshalAttributeXML(d, tok)
+				if err != nil {
+					return err
+				}
+				thing.Attributes = append(thing.Attributes, attr)
+			case "spriteGroup":
+				sprGr := &SpriteGroup{}
+				if err = d.DecodeElement(sprGr, &tok); err != nil {
+					return err
+				}
+				thing.SpriteGroups = append(thing.SpriteGroups, sprGr)
+			default:
+				if err = d.Skip(); err != nil {
+					return err
+				}
+			}
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+// spriteGroup has the same fields as SpriteGroup, but none of its methods
+type spriteGroup SpriteGroup
+
+// xmlSpriteGroup is XML representation of SpriteGroup with sprite IDs
+// encoded as space separated list
+type xmlSpriteGroup struct {
+	*spriteGroup
+	Sprites string `xml:"sprites"`
+}
+
+// MarshalXML implements xml.Marshaler interface
+func (sprGr *SpriteGroup) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	sprites := make([]string, len(sprGr.Sprites))
+	for i, sprID := range sprGr.Sprites {
+		sprites[i] = strconv.FormatUint(uint64(sprID), 10)
+	}
+	return e.EncodeElement(&xmlSpriteGroup{
+		(*spriteGroup)(sprGr),
+		strings.Join(sprites, " "),
+	}, start)
+}
+
+// UnmarshalXML implements xml.Unmarshaler interface
+func (sprGr *SpriteGroup) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	doc := &xmlSpriteGroup{spriteGroup: (*spriteGroup)(sprGr)}
+	if err := d.DecodeElement(doc, &start); err != nil {
+		return err
+	}
+	if sprGr.AnimationPhases == nil {
+		sprGr.AnimationPhases = make([]*AnimationPhase, 0)
+	}
+	fields := strings.Fields(doc.Sprites)
+	sprGr.Sprites = nil
+	if len(fields) > 0 {
+		sprGr.Sprites = make([]uint32, 0, len(fields))
+	}
+	for _, field := range fields {
+		sprID, err := strconv.ParseUint(field, 10, 32)
+		if err != nil {
+			return err
+		}
+		sprGr.Sprites = append(sprGr.Sprites, uint32(sprID))
+	}
+	return nil
+}
+
+func marshalAttributeXML(e *xml.Encoder, attr Attribute, rgb bool) error {
+	start := xml.StartElement{Name: xml.Name{Local: "attr"}}
+	if colored, ok := attr.(coloredAttribute); ok && rgb {
+		start.Attr = append(start.Attr, xml.Attr{
+			Name:  xml.Name{Local: "rgb"},
+			Value: FormatRGB(colored.RGB()),
+		})
+	}
+	return e.EncodeElement(attr, start)
+}
+
+func unmarshalAttributeXML(d *xml.Decoder, start xml.StartElement) (Attribute, error) {
+	var name, rgb string
+	for _, xmlAttr := range start.Attr {
+		switch xmlAttr.Name.Local {
+		case "name":
+			name = xmlAttr.Value
+		case "rgb":
+			rgb = xmlAttr.Value
+		}
+	}
+	attr, err := newAttributeByName(name)
+	if err != nil {
+		return nil, err
+	}
+	if err = applyRGB(attr, rgb); err != nil {
+		return nil, err
+	}
+	if err = d.DecodeElement(attr, &start); err != nil {
+		return nil, err
+	}
+	return attr, nil
+}
@@ -0,0 +1,47 @@
+package dat
+
+import (
+	"bytes"
+	"encoding/xml"
+	"testing"
+)
+
+func TestXMLRoundTrip(t *testing.T) {
+	data := encodeTestFile(t, newTestFile(9))
+	datfh, err := openSequential(writeTestFile(t, data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	doc, err := xml.MarshalIndent(datfh, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := NewFile(0)
+	if err = xml.Unmarshal(doc, decoded); err != nil {
+		t.Fatal(err)
+	}
+	if got := encodeTestFile(t, decoded); !bytes.Equal(got, data) {
+		t.Errorf("binary of XML round trip differs: got %d bytes, want %d bytes", len(got), len(data))
+	}
+}
+
+func TestUnmarshalXMLThingIDs(t *testing.T) {
+	tests := []struct {
+		items string
+		valid bool
+	}{
+		{`<thing id="100" type="item"></thing><thing id="101" type="item"></thing>`, true},
+		{`<thing id="100" type="item"></thing><thing id="102" type="item"></thing>`, false},
+		{`<thing id="101" type="item"></thing><thing id="100" type="item"></thing>`, false},
+		{`<thing id="1" type="item"></thing>`, false},
+	}
+	for _, test := range tests {
+		doc := `<dat signature="1" contentRevision="1"><items>` + test.items +
+			`</items><outfits></outfits><effects></effects><missiles></missiles></dat>`
+		err := xml.Unmarshal([]byte(doc), NewFile(0))
+		if test.valid != (err == nil) {
+			t.Errorf("%s: got error %v", test.items, err)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package otb
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	bin "github.com/go-otserv/encoding/binary"
+)
+
+// Special bytes of node tree, other occurrences of these bytes in node data
+// are preceded by nodeEscape
+const (
+	nodeStart  = 0xFE
+	nodeEnd    = 0xFF
+	nodeEscape = 0xFD
+)
+
+// node is single node of OTB tree: type, unescaped data and child nodes
+type node struct {
+	typ      uint8
+	data     []byte
+	children []*node
+}
+
+// readNode parses node following already read nodeStart byte
+func readNode(otbfh *bin.BufferedFile) (*node, error) {
+	typ, err := otbfh.UInt8()
+	if err != nil {
+		return nil, err
+	}
+	n := &node{typ: typ, data: make([]byte, 0)}
+	for {
+		var b uint8
+		if b, err = otbfh.UInt8(); err != nil {
+			return nil, err
+		}
+		switch b {
+		case nodeStart:
+			child, err := readNode(otbfh)
+			if err != nil {
+				return nil, err
+			}
+			n.children = append(n.children, child)
+		case nodeEnd:
+			return n, nil
+		case nodeEscape:
+			if b, err = otbfh.UInt8(); err != nil {
+				return nil, err
+			}
+			n.data = append(n.data, b)
+		default:
+			if len(n.children) > 0 {
+				return nil, fmt.Errorf("Unexpected data after child nodes of node type %d", n.typ)
+			}
+			n.data = append(n.data, b)
+		}
+	}
+}
+
+// attribute is single type-length-value entry of node data
+type attribute struct {
+	typ  uint8
+	data []byte
+}
+
+// parseAttributes splits data into attributes, each of them is uint8 type,
+// uint16 length and data of that length
+func parseAttributes(data []byte) ([]attribute, error) {
+	attrs := make([]attribute, 0)
+	attrfh := bin.NewBufferedReader(bytes.NewReader(data))
+	for {
+		typ, err := attrfh.UInt8()
+		if err == io.EOF {
+			return attrs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		var size uint16
+		if size, err = attrfh.UInt16(); err != nil {
+			return nil, fmt.Errorf("Truncated header of attribute 0x%02X", typ)
+		}
+		attr := attribute{typ, make([]byte, size)}
+		if _, err = io.ReadFull(attrfh, attr.data); err != nil {
+			return nil, fmt.Errorf("Attribute 0x%02X of size %d exceeds node data", typ, size)
+		}
+		attrs = append(attrs, attr)
+	}
+}
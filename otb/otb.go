@@ -0,0 +1,300 @@
+// Package otb provides reading of items.otb files, which describe items of
+// server: their server IDs, client IDs of .dat items, groups, flags and
+// names.
+package otb
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	bin "github.com/go-otserv/encoding/binary"
+)
+
+// Attributes of root node
+const (
+	RootAttrVersion = 0x01
+)
+
+// Attributes of item nodes
+const (
+	AttrServerID     = 0x10
+	AttrClientID     = 0x11
+	AttrName         = 0x12
+	AttrSpeed        = 0x14
+	AttrSpriteHash   = 0x20
+	AttrMinimapColor = 0x21
+	AttrLight2       = 0x2A
+	AttrTopOrder     = 0x2B
+	AttrWareID       = 0x2D
+)
+
+// csdVersionSize is size of NUL padded CSD version string of root version
+// attribute
+const csdVersionSize = 128
+
+// ItemGroup is type of item node
+type ItemGroup uint8
+
+// Item groups
+const (
+	GroupNone ItemGroup = iota
+	GroupGround
+	GroupContainer
+	GroupWeapon
+	GroupAmmunition
+	GroupArmor
+	GroupCharges
+	GroupTeleport
+	GroupMagicField
+	GroupWriteable
+	GroupKey
+	GroupSplash
+	GroupFluid
+	GroupDoor
+	GroupDeprecated
+)
+
+var itemGroupNames = []string{
+	"none", "ground", "container", "weapon", "ammunition", "armor", "charges",
+	"teleport", "magicField", "writeable", "key", "splash", "fluid", "door",
+	"deprecated",
+}
+
+// String returns name of group
+func (group ItemGroup) String() string {
+	if int(group) < len(itemGroupNames) {
+		return itemGroupNames[group]
+	}
+	return fmt.Sprintf("ItemGroup<%d>", uint8(group))
+}
+
+// Flags of item nodes
+const (
+	FlagBlockSolid uint32 = 1 << iota
+	FlagBlockProjectile
+	FlagBlockPathfind
+	FlagHasHeight
+	FlagUseable
+	FlagPickupable
+	FlagMoveable
+	FlagStackable
+	FlagFloorChangeDown
+	FlagFloorChangeNorth
+	FlagFloorChangeEast
+	FlagFloorChangeSouth
+	FlagFloorChangeWest
+	FlagAlwaysOnTop
+	FlagReadable
+	FlagRotatable
+	FlagHangable
+	FlagVertical
+	FlagHorizontal
+	FlagCannotDecay
+	FlagAllowDistRead
+	FlagUnused
+	FlagClientCharges
+	FlagLookThrough
+	FlagAnimation
+	FlagFullTile
+	FlagForceUse
+)
+
+// File holds version of items.otb file and its items in order of
+// appearance
+type File struct {
+	Flags        uint32
+	MajorVersion uint32
+	MinorVersion uint32
+	BuildNumber  uint32
+	CSDVersion   string
+	Items        []*Item
+	byServerID   map[uint16]*Item
+	byClientID   map[uint16][]*Item
+}
+
+// Item holds single item of items.otb. Attributes not decoded into fields
+// are kept in Unknown by their types
+type Item struct {
+	Group        ItemGroup
+	Flags        uint32
+	ServerID     uint16
+	ClientID     uint16
+	Name         string
+	Speed        uint16
+	SpriteHash   []byte
+	MinimapColor uint16
+	LightLevel   uint16
+	LightColor   uint16
+	TopOrder     uint8
+	WareID       uint16
+	Unknown      map[uint8][]byte
+}
+
+// Has reports whether item has all given flags
+func (item *Item) Has(flags uint32) bool {
+	return item.Flags&flags == flags
+}
+
+// Open reads items.otb file at given path
+func Open(path string) (*File, error) {
+	fh, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+	return Read(fh)
+}
+
+// Read parses items.otb document. Server IDs of items have to be unique
+func Read(r io.Reader) (*File, error) {
+	fh := bin.NewBufferedReader(r)
+	// file identifier, zero for items.otb
+	if _, err := fh.UInt32(); err != nil {
+		return nil, err
+	}
+	start, err := fh.UInt8()
+	if err != nil {
+		return nil, err
+	}
+	if start != nodeStart {
+		return nil, fmt.Errorf("Expected start of root node, got 0x%02X", start)
+	}
+	root, err := readNode(fh)
+	if err != nil {
+		return nil, err
+	}
+
+	otbfh := &File{
+		Items:      make([]*Item, 0, len(root.children)),
+		byServerID: make(map[uint16]*Item),
+		byClientID: make(map[uint16][]*Item),
+	}
+	if err = otbfh.parseRoot(root.data); err != nil {
+		return nil, err
+	}
+	for _, child := range root.children {
+		item, err := parseItem(child)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := otbfh.byServerID[item.ServerID]; ok {
+			return nil, fmt.Errorf("Duplicated server ID %d", item.ServerID)
+		}
+		otbfh.Items = append(otbfh.Items, item)
+		otbfh.byServerID[item.ServerID] = item
+		otbfh.byClientID[item.ClientID] = append(otbfh.byClientID[item.ClientID], item)
+	}
+	return otbfh, nil
+}
+
+func (otbfh *File) parseRoot(data []byte) error {
+	if len(data) < 4 {
+		return fmt.Errorf("Root node too short: %d bytes", len(data))
+	}
+	rootfh := bin.NewBufferedReader(bytes.NewReader(data))
+	var err error
+	if otbfh.Flags, err = rootfh.UInt32(); err != nil {
+		return err
+	}
+	attrs, err := parseAttributes(data[4:])
+	if err != nil {
+		return err
+	}
+	for _, attr := range attrs {
+		if attr.typ != RootAttrVersion {
+			continue
+		}
+		if len(attr.data) != 12+csdVersionSize {
+			return fmt.Errorf("Invalid size of version attribute: %d", len(attr.data))
+		}
+		versionfh := bin.NewBufferedReader(bytes.NewReader(attr.data))
+		if otbfh.MajorVersion, err = versionfh.UInt32(); err != nil {
+			return err
+		}
+		if otbfh.MinorVersion, err = versionfh.UInt32(); err != nil {
+			return err
+		}
+		if otbfh.BuildNumber, err = versionfh.UInt32(); err != nil {
+			return err
+		}
+		otbfh.CSDVersion = strings.TrimRight(string(attr.data[12:]), "\x00")
+	}
+	return nil
+}
+
+func parseItem(n *node) (*Item, error) {
+	if len(n.data) < 4 {
+		return nil, fmt.Errorf("Item node too short: %d bytes", len(n.data))
+	}
+	item := &Item{Group: ItemGroup(n.typ), Unknown: make(map[uint8][]byte)}
+	itemfh := bin.NewBufferedReader(bytes.NewReader(n.data))
+	var err error
+	if item.Flags, err = itemfh.UInt32(); err != nil {
+		return nil, err
+	}
+	attrs, err := parseAttributes(n.data[4:])
+	if err != nil {
+		return nil, err
+	}
+
+	for _, attr := range attrs {
+		attrfh := bin.NewBufferedReader(bytes.NewReader(attr.data))
+		switch attr.typ {
+		case AttrServerID:
+			item.ServerID, err = attrfh.UInt16()
+		case AttrClientID:
+			item.ClientID, err = attrfh.UInt16()
+		case AttrName:
+			item.Name = string(attr.data)
+		case AttrSpeed:
+			item.Speed, err = attrfh.UInt16()
+		case AttrSpriteHash:
+			item.SpriteHash = attr.data
+		case AttrMinimapColor:
+			item.MinimapColor, err = attrfh.UInt16()
+		case AttrLight2:
+			if item.LightLevel, err = attrfh.UInt16(); err == nil {
+				item.LightColor, err = attrfh.UInt16()
+			}
+		case AttrTopOrder:
+			item.TopOrder, err = attrfh.UInt8()
+		case AttrWareID:
+			item.WareID, err = attrfh.UInt16()
+		default:
+			item.Unknown[attr.typ] = attr.data
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Invalid attribute 0x%02X of item: %v", attr.typ, err)
+		}
+	}
+	return item, nil
+}
+
+// Item returns item of given server ID or nil
+func (otbfh *File) Item(serverID uint16) *Item {
+	return otbfh.byServerID[serverID]
+}
+
+// ClientID returns client ID of item of given server ID, second returned
+// value is false if there is no such item
+func (otbfh *File) ClientID(serverID uint16) (uint16, bool) {
+	item, ok := otbfh.byServerID[serverID]
+	if !ok {
+		return 0, false
+	}
+	return item.ClientID, true
+}
+
+// ServerIDs returns server IDs of all items of given client ID, several
+// server items might share one client item
+func (otbfh *File) ServerIDs(clientID uint16) []uint16 {
+	items := otbfh.byClientID[clientID]
+	ids := make([]uint16, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, item.ServerID)
+	}
+	return ids
+}
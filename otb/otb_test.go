@@ -0,0 +1,135 @@
+package otb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// escape precedes special bytes of node data by nodeEscape
+func escape(data []byte) []byte {
+	escaped := make([]byte, 0, len(data))
+	for _, b := range data {
+		if b == nodeStart || b == nodeEnd || b == nodeEscape {
+			escaped = append(escaped, nodeEscape)
+		}
+		escaped = append(escaped, b)
+	}
+	return escaped
+}
+
+// attr returns attribute of given type and data
+func attr(typ uint8, data ...byte) []byte {
+	return append([]byte{typ, byte(len(data)), byte(len(data) >> 8)}, data...)
+}
+
+func u16(val uint16) []byte {
+	return binary.LittleEndian.AppendUint16(nil, val)
+}
+
+// testItem returns node data of item of given flags and attributes
+func testItem(flags uint32, attrs ...[]byte) []byte {
+	data := binary.LittleEndian.AppendUint32(nil, flags)
+	for _, a := range attrs {
+		data = append(data, a...)
+	}
+	return data
+}
+
+// testOTB returns items.otb document of given items, keyed by their groups.
+// Version numbers contain special bytes to check escaping of root node
+func testOTB(groups []ItemGroup, items [][]byte) []byte {
+	version := binary.LittleEndian.AppendUint32(nil, 3)
+	version = binary.LittleEndian.AppendUint32(version, 0xFF)
+	version = binary.LittleEndian.AppendUint32(version, 0xFDFE)
+	csd := make([]byte, csdVersionSize)
+	copy(csd, "OTB 3.57-10.98")
+	root := binary.LittleEndian.AppendUint32(nil, 0)
+	root = append(root, attr(RootAttrVersion, append(version, csd...)...)...)
+
+	doc := []byte{0, 0, 0, 0, nodeStart, 0}
+	doc = append(doc, escape(root)...)
+	for i, item := range items {
+		doc = append(doc, nodeStart, byte(groups[i]))
+		doc = append(doc, escape(item)...)
+		doc = append(doc, nodeEnd)
+	}
+	return append(doc, nodeEnd)
+}
+
+func TestRead(t *testing.T) {
+	doc := testOTB([]ItemGroup{GroupGround, GroupContainer, GroupNone}, [][]byte{
+		testItem(FlagBlockSolid|FlagFullTile,
+			attr(AttrServerID, u16(100)...), attr(AttrClientID, u16(0x00FE)...),
+			attr(AttrSpeed, u16(150)...), attr(AttrMinimapColor, u16(0xFF)...)),
+		testItem(FlagPickupable|FlagMoveable|FlagUseable,
+			attr(AttrServerID, u16(0xFDFF)...), attr(AttrClientID, u16(0x00FE)...),
+			attr(AttrName, []byte("bag\xfe")...), attr(AttrLight2, 7, 0, 0xFD, 0),
+			attr(AttrTopOrder, 0xFF), attr(AttrWareID, u16(0xFEFF)...),
+			attr(AttrSpriteHash, 0xFD, 0xFE, 0xFF, 0), attr(0x99, 5, 0xFF)),
+		testItem(0, attr(AttrServerID, u16(101)...), attr(AttrClientID, u16(200)...)),
+	})
+
+	otbfh, err := Read(bytes.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if otbfh.MajorVersion != 3 || otbfh.MinorVersion != 0xFF || otbfh.BuildNumber != 0xFDFE ||
+		otbfh.CSDVersion != "OTB 3.57-10.98" {
+		t.Errorf("got version %d.%d.%d %q", otbfh.MajorVersion, otbfh.MinorVersion,
+			otbfh.BuildNumber, otbfh.CSDVersion)
+	}
+	if len(otbfh.Items) != 3 {
+		t.Fatalf("got %d items, want 3", len(otbfh.Items))
+	}
+
+	ground := otbfh.Item(100)
+	if ground == nil || ground.Group != GroupGround || !ground.Has(FlagBlockSolid|FlagFullTile) ||
+		ground.Has(FlagPickupable) || ground.Speed != 150 || ground.MinimapColor != 0xFF {
+		t.Errorf("ground: %+v", ground)
+	}
+
+	bag := otbfh.Item(0xFDFF)
+	switch {
+	case bag == nil:
+		t.Fatal("item 0xFDFF is missing")
+	case bag.Group != GroupContainer || bag.Flags != FlagPickupable|FlagMoveable|FlagUseable:
+		t.Errorf("bag: group %s, flags %b", bag.Group, bag.Flags)
+	case bag.Name != "bag\xfe" || bag.LightLevel != 7 || bag.LightColor != 0xFD ||
+		bag.TopOrder != 0xFF || bag.WareID != 0xFEFF:
+		t.Errorf("bag: %+v", bag)
+	case !bytes.Equal(bag.SpriteHash, []byte{0xFD, 0xFE, 0xFF, 0}):
+		t.Errorf("bag: sprite hash %x", bag.SpriteHash)
+	case !bytes.Equal(bag.Unknown[0x99], []byte{5, 0xFF}):
+		t.Errorf("bag: unknown attributes %v", bag.Unknown)
+	}
+
+	if clientID, ok := otbfh.ClientID(101); !ok || clientID != 200 {
+		t.Errorf("ClientID(101) = %d, %v, want 200", clientID, ok)
+	}
+	if _, ok := otbfh.ClientID(102); ok {
+		t.Error("ClientID(102) found missing item")
+	}
+	if ids := otbfh.ServerIDs(0x00FE); len(ids) != 2 || ids[0] != 100 || ids[1] != 0xFDFF {
+		t.Errorf("ServerIDs(0xFE) = %v, want [100 0xFDFF]", ids)
+	}
+}
+
+func TestReadDuplicatedServerID(t *testing.T) {
+	item := testItem(0, attr(AttrServerID, u16(100)...), attr(AttrClientID, u16(100)...))
+	doc := testOTB([]ItemGroup{GroupNone, GroupNone}, [][]byte{item, item})
+	if _, err := Read(bytes.NewReader(doc)); err == nil {
+		t.Error("Read succeeded with duplicated server ID, want error")
+	}
+}
+
+func TestReadTruncated(t *testing.T) {
+	doc := testOTB([]ItemGroup{GroupNone}, [][]byte{
+		testItem(0, attr(AttrServerID, u16(100)...), attr(AttrName, []byte("stone")...)),
+	})
+	for size := 0; size < len(doc); size++ {
+		if _, err := Read(bytes.NewReader(doc[:size])); err == nil {
+			t.Errorf("Read of %d bytes succeeded, want error", size)
+		}
+	}
+}
@@ -0,0 +1,172 @@
+// Package otfi provides reading and writing of .otfi files, which describe
+// format of .dat and .spr files pair in OTML syntax, as used by Object
+// Builder and OTClient.
+package otfi
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/go-otserv/encoding/dat"
+	"github.com/go-otserv/encoding/spr"
+)
+
+// Keys of DatSpr node. Object Builder stores improved animations as frame
+// durations, improved-animations is accepted as well
+const (
+	keyDatSpr             = "DatSpr"
+	keyExtended           = "extended"
+	keyTransparency       = "transparency"
+	keyFrameDurations     = "frame-durations"
+	keyImprovedAnimations = "improved-animations"
+	keyFrameGroups        = "frame-groups"
+	keyMetadataFile       = "metadata-file"
+	keySpritesFile        = "sprites-file"
+)
+
+// File holds features of .dat and .spr files and their names. Dir is
+// directory names of files are relative to, Open sets it to directory of
+// .otfi file
+type File struct {
+	Extended           bool
+	Transparency       bool
+	FrameGroups        bool
+	ImprovedAnimations bool
+	MetadataFile       string
+	SpritesFile        string
+	Dir                string
+}
+
+// Open reads .otfi file at given path. Missing names of .dat and .spr files
+// default to name of .otfi file with .dat and .spr extension
+func Open(path string) (*File, error) {
+	fh, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+
+	otfh, err := Read(fh)
+	if err != nil {
+		return nil, err
+	}
+	otfh.Dir = filepath.Dir(path)
+	base := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	if otfh.MetadataFile == "" {
+		otfh.MetadataFile = base + ".dat"
+	}
+	if otfh.SpritesFile == "" {
+		otfh.SpritesFile = base + ".spr"
+	}
+	return otfh, nil
+}
+
+// Read parses .otfi document. Unknown keys are ignored, missing flags are
+// false
+func Read(r io.Reader) (*File, error) {
+	root, err := parseOTML(r)
+	if err != nil {
+		return nil, err
+	}
+	datSpr := root.child(keyDatSpr)
+	if datSpr == nil {
+		return nil, fmt.Errorf("Missing %s node", keyDatSpr)
+	}
+
+	otfh := &File{}
+	flags := map[string]*bool{
+		keyExtended:           &otfh.Extended,
+		keyTransparency:       &otfh.Transparency,
+		keyFrameDurations:     &otfh.ImprovedAnimations,
+		keyImprovedAnimations: &otfh.ImprovedAnimations,
+		keyFrameGroups:        &otfh.FrameGroups,
+	}
+	for _, n := range datSpr.children {
+		switch n.tag {
+		case keyMetadataFile:
+			otfh.MetadataFile = n.value
+		case keySpritesFile:
+			otfh.SpritesFile = n.value
+		default:
+			flag, ok := flags[n.tag]
+			if !ok {
+				continue
+			}
+			if *flag, err = strconv.ParseBool(n.value); err != nil {
+				return nil, fmt.Errorf("Invalid value of %s: %q", n.tag, n.value)
+			}
+		}
+	}
+	return otfh, nil
+}
+
+// Write writes .otfi document, names of files are written only if set
+func (otfh *File) Write(w io.Writer) error {
+	datSpr := &node{tag: keyDatSpr, children: []*node{
+		{tag: keyExtended, value: strconv.FormatBool(otfh.Extended)},
+		{tag: keyTransparency, value: strconv.FormatBool(otfh.Transparency)},
+		{tag: keyFrameDurations, value: strconv.FormatBool(otfh.ImprovedAnimations)},
+		{tag: keyFrameGroups, value: strconv.FormatBool(otfh.FrameGroups)},
+	}}
+	if otfh.MetadataFile != "" {
+		datSpr.children = append(datSpr.children, &node{tag: keyMetadataFile, value: otfh.MetadataFile})
+	}
+	if otfh.SpritesFile != "" {
+		datSpr.children = append(datSpr.children, &node{tag: keySpritesFile, value: otfh.SpritesFile})
+	}
+	return writeOTML(w, []*node{datSpr}, 0)
+}
+
+// Save writes .otfi document to file at given path
+func (otfh *File) Save(path string) error {
+	fh, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err = otfh.Write(fh); err != nil {
+		fh.Close()
+		return err
+	}
+	return fh.Close()
+}
+
+// DatFeatures returns features of .dat file
+func (otfh *File) DatFeatures() dat.Features {
+	return dat.Features{
+		Extended:           otfh.Extended,
+		FrameGroups:        otfh.FrameGroups,
+		ImprovedAnimations: otfh.ImprovedAnimations,
+	}
+}
+
+// SprFeatures returns features of .spr file
+func (otfh *File) SprFeatures() spr.Features {
+	return spr.Features{Extended: otfh.Extended, Transparency: otfh.Transparency}
+}
+
+// OpenDat opens .dat file described by .otfi file
+func (otfh *File) OpenDat() (*dat.File, error) {
+	if otfh.MetadataFile == "" {
+		return nil, fmt.Errorf("Missing %s", keyMetadataFile)
+	}
+	return dat.OpenWithFeatures(otfh.path(otfh.MetadataFile), otfh.DatFeatures())
+}
+
+// OpenSpr opens .spr file described by .otfi file
+func (otfh *File) OpenSpr() (*spr.File, error) {
+	if otfh.SpritesFile == "" {
+		return nil, fmt.Errorf("Missing %s", keySpritesFile)
+	}
+	return spr.OpenWithFeatures(otfh.path(otfh.SpritesFile), otfh.SprFeatures())
+}
+
+func (otfh *File) path(name string) string {
+	if filepath.IsAbs(name) || otfh.Dir == "" {
+		return name
+	}
+	return filepath.Join(otfh.Dir, name)
+}
@@ -0,0 +1,113 @@
+package otfi
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-otserv/encoding/dat"
+	"github.com/go-otserv/encoding/spr"
+)
+
+func TestRead(t *testing.T) {
+	// as written by Object Builder
+	doc := `DatSpr
+  extended: true
+  transparency: false
+  frame-durations: true
+  frame-groups: true
+  metadata-file: Tibia.dat
+  sprites-file: Tibia.spr
+`
+	otfh, err := Read(strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &File{Extended: true, FrameGroups: true, ImprovedAnimations: true,
+		MetadataFile: "Tibia.dat", SpritesFile: "Tibia.spr"}
+	if !reflect.DeepEqual(otfh, want) {
+		t.Errorf("got %+v, want %+v", otfh, want)
+	}
+	if features := otfh.DatFeatures(); features != (dat.Features{Extended: true, FrameGroups: true,
+		ImprovedAnimations: true}) {
+		t.Errorf("got .dat features %+v", features)
+	}
+	if features := otfh.SprFeatures(); features != (spr.Features{Extended: true}) {
+		t.Errorf("got .spr features %+v", features)
+	}
+
+	otfh, err = Read(strings.NewReader("DatSpr\n  improved-animations: true\n  unknown: 5\n"))
+	if err != nil || !otfh.ImprovedAnimations || otfh.Extended {
+		t.Errorf("improved-animations: got %+v, %v", otfh, err)
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	docs := []string{
+		"",
+		"Other\n  extended: true\n",
+		"DatSpr\n  extended: yes please\n",
+		"DatSpr\n\ttransparency: true\n",
+	}
+	for _, doc := range docs {
+		if _, err := Read(strings.NewReader(doc)); err == nil {
+			t.Errorf("Read(%q) succeeded, want error", doc)
+		}
+	}
+}
+
+func TestWriteRead(t *testing.T) {
+	files := []*File{
+		{},
+		{Extended: true, Transparency: true, FrameGroups: true, ImprovedAnimations: true},
+		{Transparency: true, MetadataFile: "Tibia 7.60.dat", SpritesFile: "sprites/Tibia.spr"},
+	}
+	for _, want := range files {
+		buf := &bytes.Buffer{}
+		if err := want.Write(buf); err != nil {
+			t.Fatal(err)
+		}
+		got, err := Read(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("got %+v, want %+v", got, want)
+		}
+	}
+}
+
+func TestOpen(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "client.otfi")
+	if err := (&File{Extended: true, SpritesFile: "sprites.spr"}).Save(path); err != nil {
+		t.Fatal(err)
+	}
+	otfh, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if otfh.Dir != dir || otfh.MetadataFile != "client.dat" || otfh.SpritesFile != "sprites.spr" {
+		t.Errorf("got %+v", otfh)
+	}
+
+	// .spr file of 0 sprites
+	sprPath := filepath.Join(dir, "sprites.spr")
+	if err = os.WriteFile(sprPath, []byte{1, 0, 0, 0, 0, 0, 0, 0}, 0644); err != nil {
+		t.Fatal(err)
+	}
+	sprfh, err := otfh.OpenSpr()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sprfh.Close()
+	if sprfh.Signature != 1 || sprfh.SpritesCount != 0 {
+		t.Errorf("opened .spr file has signature %d and %d sprites", sprfh.Signature, sprfh.SpritesCount)
+	}
+	if _, err = otfh.OpenDat(); err == nil {
+		t.Error("OpenDat of missing file succeeded")
+	}
+}
@@ -0,0 +1,101 @@
+package otfi
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// node is single OTML node: tag with optional value and children nodes
+// nested by indentation
+type node struct {
+	tag      string
+	value    string
+	children []*node
+}
+
+// child returns first child node of given tag or nil
+func (n *node) child(tag string) *node {
+	for _, child := range n.children {
+		if child.tag == tag {
+			return child
+		}
+	}
+	return nil
+}
+
+// parseOTML parses OTML document into root node holding top level nodes as
+// children. Comments starting with // are skipped
+func parseOTML(r io.Reader) (*node, error) {
+	type level struct {
+		indent int
+		node   *node
+	}
+	root := &node{}
+	stack := []level{{-1, root}}
+
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		content := strings.TrimLeft(line, " ")
+		if content == "" || strings.HasPrefix(content, "//") {
+			continue
+		}
+		if strings.HasPrefix(content, "\t") {
+			return nil, fmt.Errorf("Line %d: tabs are not allowed in indentation", lineNum)
+		}
+		indent := len(line) - len(content)
+
+		n := &node{tag: content}
+		if strings.HasPrefix(content, "- ") {
+			n.tag, n.value = "", unquote(strings.TrimSpace(content[2:]))
+		} else if sep := strings.Index(content, ":"); sep >= 0 {
+			n.tag = strings.TrimSpace(content[:sep])
+			n.value = unquote(strings.TrimSpace(content[sep+1:]))
+		}
+
+		for stack[len(stack)-1].indent >= indent {
+			stack = stack[:len(stack)-1]
+		}
+		parent := stack[len(stack)-1].node
+		parent.children = append(parent.children, n)
+		stack = append(stack, level{indent, n})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return root, nil
+}
+
+func unquote(value string) string {
+	if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
+// writeOTML writes nodes indented by two spaces per level of depth
+func writeOTML(w io.Writer, nodes []*node, depth int) error {
+	indent := strings.Repeat("  ", depth)
+	for _, n := range nodes {
+		var err error
+		switch {
+		case n.tag == "":
+			_, err = fmt.Fprintf(w, "%s- %s\n", indent, n.value)
+		case n.value == "":
+			_, err = fmt.Fprintf(w, "%s%s\n", indent, n.tag)
+		default:
+			_, err = fmt.Fprintf(w, "%s%s: %s\n", indent, n.tag, n.value)
+		}
+		if err != nil {
+			return err
+		}
+		if err = writeOTML(w, n.children, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package otfi
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseOTML(t *testing.T) {
+	doc := `// comment
+DatSpr
+  extended: true
+  metadata-file: "Tibia 10.98.dat"
+
+  Nested
+    deep: value: with colon
+    - first
+    - second
+Other: 1
+`
+	root, err := parseOTML(strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &node{children: []*node{
+		{tag: "DatSpr", children: []*node{
+			{tag: "extended", value: "true"},
+			{tag: "metadata-file", value: "Tibia 10.98.dat"},
+			{tag: "Nested", children: []*node{
+				{tag: "deep", value: "value: with colon"},
+				{value: "first"},
+				{value: "second"},
+			}},
+		}},
+		{tag: "Other", value: "1"},
+	}}
+	if !reflect.DeepEqual(root, want) {
+		t.Errorf("got tree %s, want %s", dumpNodes(root.children), dumpNodes(want.children))
+	}
+
+	buf := &bytes.Buffer{}
+	if err = writeOTML(buf, root.children, 0); err != nil {
+		t.Fatal(err)
+	}
+	reparsed, err := parseOTML(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(reparsed, root) {
+		t.Errorf("written document parses into %s", dumpNodes(reparsed.children))
+	}
+}
+
+func TestParseOTMLTabs(t *testing.T) {
+	if _, err := parseOTML(strings.NewReader("DatSpr\n\textended: true\n")); err == nil {
+		t.Error("parseOTML accepted tab indentation")
+	}
+}
+
+// dumpNodes returns nodes written as OTML document
+func dumpNodes(nodes []*node) string {
+	buf := &bytes.Buffer{}
+	writeOTML(buf, nodes, 0)
+	return "\n" + buf.String()
+}
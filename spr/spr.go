@@ -3,6 +3,7 @@ package spr
 import (
 	"fmt"
 	"image"
+	"io"
 
 	bin "github.com/go-otserv/encoding/binary"
 )
@@ -15,30 +16,85 @@ type File struct {
 	SpriteOffset    int
 	SpriteDataSize  int
 	HasAplhaChannel bool
+	Extended        bool
 }
 
-// Open opens given file for reading
+// Features describes optional parts of .spr format, which depend on client
+// version and are usually configured by .otfi file
+type Features struct {
+	// Extended stores sprites count as uint32 instead of uint16
+	Extended bool
+	// Transparency stores alpha channel of sprite pixels
+	Transparency bool
+}
+
+// Open opens given extended file for reading
 func Open(path string, hasAplhaChannel bool) (*File, error) {
+	return OpenWithFeatures(path, Features{Extended: true, Transparency: hasAplhaChannel})
+}
+
+// OpenWithFeatures opens given file of given features for reading, see otfi
+// package for reading features from .otfi file
+func OpenWithFeatures(path string, features Features) (*File, error) {
 	fh, err := bin.OpenFile(path)
 	if err != nil {
 		return nil, err
 	}
-	sprfh := &File{*fh, 0, 0, 0, 0, hasAplhaChannel}
+	sprfh := &File{*fh, 0, 0, 0, 0, features.Transparency, features.Extended}
 
 	sprfh.Signature, err = sprfh.UInt32()
 	if err != nil {
 		return sprfh, err
 	}
-	sprfh.SpritesCount, err = sprfh.UInt32()
+	if features.Extended {
+		sprfh.SpritesCount, err = sprfh.UInt32()
+		sprfh.SpriteOffset = 8
+	} else {
+		var count uint16
+		count, err = sprfh.UInt16()
+		sprfh.SpritesCount = uint32(count)
+		sprfh.SpriteOffset = 6
+	}
 	if err != nil {
 		return sprfh, err
 	}
-	sprfh.SpriteOffset = 8
 	sprfh.SpriteDataSize = 32 * 32 * 4
 
 	return sprfh, nil
 }
 
+// SpriteAddress returns offset of data of sprite of given id in .spr file,
+// zero means sprite is empty. Sprite 0 is always empty
+func (sprfh *File) SpriteAddress(id int) (uint32, error) {
+	if id == 0 {
+		return 0, nil
+	}
+	if id < 0 || uint32(id) > sprfh.SpritesCount {
+		return 0, fmt.Errorf("Sprite %d out of range, file has %d sprites", id, sprfh.SpritesCount)
+	}
+	sprfh.Seek(int64(((id-1)*4)+sprfh.SpriteOffset), 0)
+	return sprfh.UInt32()
+}
+
+// RawSprite returns encoded pixel data of sprite of given id, without color
+// key and size, as stored in .spr file. It returns nil for empty sprite
+func (sprfh *File) RawSprite(id int) ([]byte, error) {
+	sprAddress, err := sprfh.SpriteAddress(id)
+	if err != nil || sprAddress == 0 {
+		return nil, err
+	}
+	sprfh.Seek(int64(sprAddress)+3, 0)
+	size, err := sprfh.UInt16()
+	if err != nil {
+		return nil, err
+	}
+	data := make([]byte, size)
+	if _, err = io.ReadFull(sprfh, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
 // GetSprite parses .spr file to extract sprite of given id
 func (sprfh *File) GetSprite(id int) (*image.RGBA, error) {
 	img := image.NewRGBA(image.Rect(0, 0, 32, 32))
@@ -46,9 +102,7 @@ func (sprfh *File) GetSprite(id int) (*image.RGBA, error) {
 	if id == 0 {
 		return img, nil
 	}
-	sprfh.Seek(int64(((id-1)*4)+sprfh.SpriteOffset), 0)
-
-	sprAddress, err := sprfh.UInt32()
+	sprAddress, err := sprfh.SpriteAddress(id)
 	if err != nil {
 		return nil, err
 	}
@@ -119,7 +173,11 @@ func (sprfh *File) GetSprite(id int) (*image.RGBA, error) {
 			i++
 		}
 
-		readPos += 4 + (3 * coloredPixels)
+		if sprfh.HasAplhaChannel {
+			readPos += 4 + (4 * coloredPixels)
+		} else {
+			readPos += 4 + (3 * coloredPixels)
+		}
 	}
 
 	// fill remaining pixels with alpha
@@ -0,0 +1,104 @@
+package spr
+
+import (
+	"encoding/binary"
+	"image/color"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// pixelRuns returns raw sprite of given runs, every run is number of
+// transparent pixels followed by colored pixels
+func pixelRuns(alpha bool, runs ...[]color.NRGBA) []byte {
+	data := make([]byte, 0)
+	for _, run := range runs {
+		transparent := 0
+		for transparent < len(run) && run[transparent].A == 0 {
+			transparent++
+		}
+		colored := run[transparent:]
+		data = append(data, byte(transparent), byte(transparent>>8), byte(len(colored)), byte(len(colored)>>8))
+		for _, c := range colored {
+			data = append(data, c.R, c.G, c.B)
+			if alpha {
+				data = append(data, c.A)
+			}
+		}
+	}
+	return data
+}
+
+// testSprFile returns extended .spr file of given raw sprites, nil sprites
+// are empty
+func testSprFile(signature uint32, sprites [][]byte) []byte {
+	data := binary.LittleEndian.AppendUint32(nil, signature)
+	data = binary.LittleEndian.AppendUint32(data, uint32(len(sprites)))
+	address := uint32(8 + 4*len(sprites))
+	for _, sprite := range sprites {
+		if sprite == nil {
+			data = binary.LittleEndian.AppendUint32(data, 0)
+			continue
+		}
+		data = binary.LittleEndian.AppendUint32(data, address)
+		address += uint32(5 + len(sprite))
+	}
+	for _, sprite := range sprites {
+		if sprite != nil {
+			data = append(data, 0xFF, 0x00, 0xFF, byte(len(sprite)), byte(len(sprite)>>8))
+			data = append(data, sprite...)
+		}
+	}
+	return data
+}
+
+func TestGetSprite(t *testing.T) {
+	clear := color.NRGBA{}
+	red := color.NRGBA{255, 0, 0, 255}
+	green := color.NRGBA{0, 255, 0, 128}
+	blue := color.NRGBA{0, 0, 255, 64}
+	runs := [][]color.NRGBA{
+		{clear, clear, red, green, blue},
+		{clear, blue, red},
+	}
+
+	for _, alpha := range []bool{false, true} {
+		features := Features{Extended: true, Transparency: alpha}
+		path := filepath.Join(t.TempDir(), "Tibia.spr")
+		// second sprite follows the first one, so reading past the end of
+		// the first sprite doesn't go unnoticed
+		sprites := [][]byte{pixelRuns(alpha, runs...), pixelRuns(alpha, []color.NRGBA{red})}
+		if err := os.WriteFile(path, testSprFile(0x1234, sprites), 0644); err != nil {
+			t.Fatal(err)
+		}
+		sprfh, err := OpenWithFeatures(path, features)
+		if err != nil {
+			t.Fatal(err)
+		}
+		img, err := sprfh.GetSprite(1)
+		sprfh.Close()
+		if err != nil {
+			t.Fatalf("alpha=%v: %v", alpha, err)
+		}
+
+		pos := 0
+		for _, run := range runs {
+			for _, want := range run {
+				if !alpha && want.A != 0 {
+					want.A = 255
+				}
+				got := img.Pix[pos*4 : pos*4+4]
+				if got[0] != want.R || got[1] != want.G || got[2] != want.B || got[3] != want.A {
+					t.Errorf("alpha=%v: pixel %d is %v, want %v", alpha, pos, got, want)
+				}
+				pos++
+			}
+		}
+		for ; pos < 32*32; pos++ {
+			if img.Pix[pos*4+3] != 0 {
+				t.Errorf("alpha=%v: pixel %d isn't transparent: %v", alpha, pos, img.Pix[pos*4:pos*4+4])
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package spr
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	bin "github.com/go-otserv/encoding/binary"
+)
+
+// colorKey is color of transparent pixels written before every sprite
+var colorKey = []byte{0xFF, 0x00, 0xFF}
+
+// Write writes .spr file of given features made of raw sprites, as returned
+// by File.RawSprite. Sprite at index i gets ID i+1, nil sprites are written
+// as empty
+func Write(w io.Writer, signature uint32, features Features, sprites [][]byte) error {
+	sprfh := bin.NewBufferedWriter(w)
+	if err := sprfh.PutUInt32(signature); err != nil {
+		return err
+	}
+	headerSize := 8
+	if features.Extended {
+		if err := sprfh.PutUInt32(uint32(len(sprites))); err != nil {
+			return err
+		}
+	} else {
+		if len(sprites) > 0xFFFF {
+			return fmt.Errorf("Too many sprites for not extended file: %d", len(sprites))
+		}
+		if err := sprfh.PutUInt16(uint16(len(sprites))); err != nil {
+			return err
+		}
+		headerSize = 6
+	}
+
+	address := uint32(headerSize + 4*len(sprites))
+	for _, data := range sprites {
+		if len(data) > 0xFFFF {
+			return fmt.Errorf("Sprite data too long: %d", len(data))
+		}
+		if data == nil {
+			if err := sprfh.PutUInt32(0); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := sprfh.PutUInt32(address); err != nil {
+			return err
+		}
+		address += uint32(len(colorKey) + 2 + len(data))
+	}
+	for _, data := range sprites {
+		if data == nil {
+			continue
+		}
+		if _, err := sprfh.Write(colorKey); err != nil {
+			return err
+		}
+		if err := sprfh.PutUInt16(uint16(len(data))); err != nil {
+			return err
+		}
+		if _, err := sprfh.Write(data); err != nil {
+			return err
+		}
+	}
+	return sprfh.Flush()
+}
+
+// Save writes .spr file at given path, see Write
+func Save(path string, signature uint32, features Features, sprites [][]byte) error {
+	fh, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err = Write(fh, signature, features, sprites); err != nil {
+		fh.Close()
+		return err
+	}
+	return fh.Close()
+}
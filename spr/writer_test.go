@@ -0,0 +1,58 @@
+package spr
+
+import (
+	"bytes"
+	"image/color"
+	"path/filepath"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	red := color.NRGBA{255, 0, 0, 255}
+	sprites := [][]byte{
+		pixelRuns(false, []color.NRGBA{{}, red}),
+		nil,
+		pixelRuns(false, []color.NRGBA{red, red}, []color.NRGBA{{}, {}, red}),
+	}
+
+	buf := &bytes.Buffer{}
+	if err := Write(buf, 0x4A10, Features{Extended: true}, sprites); err != nil {
+		t.Fatal(err)
+	}
+	if want := testSprFile(0x4A10, sprites); !bytes.Equal(buf.Bytes(), want) {
+		t.Errorf("got file\n%v\nwant\n%v", buf.Bytes(), want)
+	}
+
+	for _, features := range []Features{{Extended: true}, {Extended: false}} {
+		path := filepath.Join(t.TempDir(), "Tibia.spr")
+		if err := Save(path, 0x4A10, features, sprites); err != nil {
+			t.Fatal(err)
+		}
+		sprfh, err := OpenWithFeatures(path, features)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sprfh.Signature != 0x4A10 || sprfh.SpritesCount != 3 {
+			t.Errorf("extended=%v: signature %X, %d sprites", features.Extended,
+				sprfh.Signature, sprfh.SpritesCount)
+		}
+		for i, want := range sprites {
+			got, err := sprfh.RawSprite(i + 1)
+			if err != nil || !bytes.Equal(got, want) || (got == nil) != (want == nil) {
+				t.Errorf("extended=%v: sprite %d is %v, %v, want %v", features.Extended, i+1, got, err, want)
+			}
+		}
+		img, err := sprfh.GetSprite(3)
+		if err != nil || img.Pix[3] != 255 || img.Pix[4*4] != 255 || img.Pix[4*4+3] != 255 {
+			t.Errorf("extended=%v: sprite 3 decoded wrong: %v", features.Extended, err)
+		}
+		sprfh.Close()
+	}
+}
+
+func TestWriteTooManySprites(t *testing.T) {
+	sprites := make([][]byte, 0x10000)
+	if err := Write(&bytes.Buffer{}, 0, Features{}, sprites); err == nil {
+		t.Error("Write of 65536 sprites into not extended file succeeded")
+	}
+}